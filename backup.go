@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hookBackup is a hook that installHook moved out of the way, stored in the
+// backup directory as <hook>.<unix-timestamp>.
+type hookBackup struct {
+	Hook      string
+	Timestamp int64
+	Path      string
+}
+
+// backupDir returns the directory holding backups of replaced hooks.
+func (hm *HookManager) backupDir() string {
+	return filepath.Join(hm.gitDir, hm.config.Settings.BackupDirectory)
+}
+
+// findBackups returns the backups in the backup directory, sorted by hook
+// name and newest first. Files that do not follow the backup naming scheme
+// are ignored, as are backups of hooks generated by hooky, which older
+// versions made when reinstalling.
+func (hm *HookManager) findBackups() ([]hookBackup, error) {
+	dir := hm.backupDir()
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []hookBackup
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		dot := strings.LastIndex(entry.Name(), ".")
+		if dot <= 0 {
+			continue
+		}
+		hook := entry.Name()[:dot]
+		timestamp, err := strconv.ParseInt(entry.Name()[dot+1:], 10, 64)
+		if err != nil || !isSupportedHook(hook) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if generated, err := isHookyHook(path); err != nil || generated {
+			continue
+		}
+		backups = append(backups, hookBackup{Hook: hook, Timestamp: timestamp, Path: path})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].Hook != backups[j].Hook {
+			return backups[i].Hook < backups[j].Hook
+		}
+		return backups[i].Timestamp > backups[j].Timestamp
+	})
+	return backups, nil
+}
+
+// ListBackups prints the available backups, newest first for each hook.
+func (hm *HookManager) ListBackups() error {
+	if err := hm.init(); err != nil {
+		return err
+	}
+
+	backups, err := hm.findBackups()
+	if err != nil {
+		return err
+	}
+
+	if len(backups) == 0 {
+		fmt.Printf("No backups found in %s\n", hm.backupDir())
+		return nil
+	}
+
+	fmt.Printf("Backups in %s:\n", hm.backupDir())
+	for _, backup := range backups {
+		created := time.Unix(backup.Timestamp, 0).Format("2006-01-02 15:04:05")
+		fmt.Printf("  %-20s %d  (%s)\n", backup.Hook, backup.Timestamp, created)
+	}
+	return nil
+}
+
+// RestoreHooks puts backed-up hooks back in place of hooky's. For each hook
+// it restores the newest backup, or with a non-zero timestamp only the
+// backups taken at that time. Hooks that exist and were not generated by
+// hooky are left alone. The restored backup is moved out of the backup
+// directory.
+func (hm *HookManager) RestoreHooks(timestamp int64) error {
+	if err := hm.init(); err != nil {
+		return err
+	}
+
+	backups, err := hm.findBackups()
+	if err != nil {
+		return err
+	}
+
+	selected := map[string]hookBackup{}
+	for _, backup := range backups {
+		if timestamp != 0 && backup.Timestamp != timestamp {
+			continue
+		}
+		// Backups are sorted newest first, so keep the first one per hook
+		if _, ok := selected[backup.Hook]; !ok {
+			selected[backup.Hook] = backup
+		}
+	}
+
+	if len(selected) == 0 {
+		if timestamp != 0 {
+			return fmt.Errorf("no backups with timestamp %d in %s (use 'hooky restore --list' to see them)", timestamp, hm.backupDir())
+		}
+		fmt.Printf("No backups found in %s\n", hm.backupDir())
+		return nil
+	}
+
+	hooksDir, err := hm.hooksDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+
+	for _, hookName := range GetSupportedHooks() {
+		backup, ok := selected[hookName]
+		if !ok {
+			continue
+		}
+
+		hookPath := filepath.Join(hooksDir, hookName)
+		if _, err := os.Stat(hookPath); err == nil {
+			generated, err := isHookyHook(hookPath)
+			if err != nil {
+				return fmt.Errorf("failed to read hook %s: %w", hookName, err)
+			}
+			if !generated {
+				fmt.Printf("Skipping %s: the installed hook was not generated by hooky\n", hookName)
+				continue
+			}
+			if err := os.Remove(hookPath); err != nil {
+				return fmt.Errorf("failed to remove hook %s: %w", hookName, err)
+			}
+		}
+
+		if err := os.Rename(backup.Path, hookPath); err != nil {
+			return fmt.Errorf("failed to restore hook %s: %w", hookName, err)
+		}
+		fmt.Printf("Restored %s from %s\n", hookName, filepath.Base(backup.Path))
+	}
+
+	return nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupBackups creates a git directory with the given backup files, each
+// holding its own name, and returns a manager for it.
+func setupBackups(t *testing.T, names ...string) (*HookManager, string) {
+	t.Helper()
+
+	gitDir := filepath.Join(t.TempDir(), ".git")
+	backupDir := filepath.Join(gitDir, ".hooky-backup")
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		t.Fatalf("Failed to create backup dir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(gitDir, "hooks"), 0755); err != nil {
+		t.Fatalf("Failed to create hooks dir: %v", err)
+	}
+
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(backupDir, name), []byte(name), 0755); err != nil {
+			t.Fatalf("Failed to write backup: %v", err)
+		}
+	}
+
+	hm := &HookManager{
+		config: &Config{
+			Hooks:    map[string][]HookScript{},
+			Settings: Settings{BackupDirectory: ".hooky-backup"},
+		},
+		gitDir: gitDir,
+	}
+	return hm, filepath.Join(gitDir, "hooks")
+}
+
+func TestFindBackups(t *testing.T) {
+	hm, _ := setupBackups(t,
+		"pre-push.1700000000",
+		"pre-commit.1600000000",
+		"pre-commit.1700000000",
+		"pre-commit.backup",
+		"not-a-hook.1700000000",
+		"README",
+	)
+
+	backups, err := hm.findBackups()
+	if err != nil {
+		t.Fatalf("findBackups failed: %v", err)
+	}
+
+	var names []string
+	for _, backup := range backups {
+		names = append(names, filepath.Base(backup.Path))
+	}
+
+	expected := "pre-commit.1700000000 pre-commit.1600000000 pre-push.1700000000"
+	if got := strings.Join(names, " "); got != expected {
+		t.Errorf("Expected backups %q, got %q", expected, got)
+	}
+}
+
+func TestFindBackupsWithoutDirectory(t *testing.T) {
+	hm := &HookManager{
+		config: &Config{Settings: Settings{BackupDirectory: ".hooky-backup"}},
+		gitDir: t.TempDir(),
+	}
+
+	backups, err := hm.findBackups()
+	if err != nil {
+		t.Fatalf("findBackups failed: %v", err)
+	}
+	if len(backups) != 0 {
+		t.Errorf("Expected no backups, got %v", backups)
+	}
+}
+
+func TestRestoreHooks(t *testing.T) {
+	tests := []struct {
+		name        string
+		timestamp   int64
+		installed   map[string]string
+		expected    map[string]string
+		expectError bool
+	}{
+		{
+			name: "newest backup replaces hooky hook",
+			installed: map[string]string{
+				"pre-commit": "#!/bin/sh\n# Generated by hooky - Do not edit manually\n",
+			},
+			expected: map[string]string{
+				"pre-commit": "pre-commit.1700000000",
+				"pre-push":   "pre-push.1700000000",
+			},
+		},
+		{
+			name:      "specific timestamp",
+			timestamp: 1600000000,
+			expected: map[string]string{
+				"pre-commit": "pre-commit.1600000000",
+			},
+		},
+		{
+			name: "hand-written hook is kept",
+			installed: map[string]string{
+				"pre-commit": "#!/bin/sh\necho custom\n",
+			},
+			expected: map[string]string{
+				"pre-commit": "#!/bin/sh\necho custom\n",
+				"pre-push":   "pre-push.1700000000",
+			},
+		},
+		{
+			name:        "unknown timestamp",
+			timestamp:   1234,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hm, hooksDir := setupBackups(t,
+				"pre-commit.1600000000",
+				"pre-commit.1700000000",
+				"pre-push.1700000000",
+			)
+			for hookName, content := range tt.installed {
+				if err := os.WriteFile(filepath.Join(hooksDir, hookName), []byte(content), 0755); err != nil {
+					t.Fatalf("Failed to write hook: %v", err)
+				}
+			}
+
+			err := hm.RestoreHooks(tt.timestamp)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RestoreHooks failed: %v", err)
+			}
+
+			for hookName, expected := range tt.expected {
+				content, err := os.ReadFile(filepath.Join(hooksDir, hookName))
+				if err != nil {
+					t.Errorf("Expected %s to be restored: %v", hookName, err)
+					continue
+				}
+				if string(content) != expected {
+					t.Errorf("Expected %s to contain %q, got %q", hookName, expected, content)
+				}
+			}
+
+			for hookName, expected := range tt.expected {
+				if strings.HasPrefix(expected, hookName+".") {
+					if _, err := os.Stat(filepath.Join(hm.backupDir(), expected)); !os.IsNotExist(err) {
+						t.Errorf("Restored backup %s should be removed from the backup directory", expected)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestUninstallAndRestore(t *testing.T) {
+	tmpDir := t.TempDir()
+	gitRun(t, tmpDir, "init", "-q")
+
+	original := "#!/bin/sh\necho original\n"
+	writeFile(t, tmpDir, ".git/hooks/pre-commit", original)
+	writeFile(t, tmpDir, "hooky.yaml", `
+hooks:
+  pre-commit:
+    - name: "echo"
+      command: "echo test"
+      description: "Echo"
+`)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(tmpDir)
+
+	if err := NewHookManager("hooky.yaml", false).InstallHooks(); err != nil {
+		t.Fatalf("InstallHooks failed: %v", err)
+	}
+	if content := readFile(t, tmpDir, ".git/hooks/pre-commit"); content == original {
+		t.Fatal("Expected the original hook to be replaced")
+	}
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.UninstallHooks(); err != nil {
+		t.Fatalf("UninstallHooks failed: %v", err)
+	}
+	if err := hm.RestoreHooks(0); err != nil {
+		t.Fatalf("RestoreHooks failed: %v", err)
+	}
+
+	if content := readFile(t, tmpDir, ".git/hooks/pre-commit"); content != original {
+		t.Errorf("Expected the original hook to be restored, got %q", content)
+	}
+}
+
+func TestReinstallAndRestore(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "echo"
+      command: "echo test"
+      description: "Echo"
+`)
+	original := "#!/bin/sh\necho original\n"
+	writeFile(t, tmpDir, ".git/hooks/pre-commit", original)
+
+	for i := 0; i < 2; i++ {
+		if err := NewHookManager("hooky.yaml", false).InstallHooks(); err != nil {
+			t.Fatalf("InstallHooks failed: %v", err)
+		}
+	}
+	// A newer backup of hooky's own hook, as older versions made on reinstall
+	generated := readFile(t, tmpDir, ".git/hooks/pre-commit")
+	writeFile(t, tmpDir, ".git/.hooky-backup/pre-commit.9999999999", generated)
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.init(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	backups, err := hm.findBackups()
+	if err != nil {
+		t.Fatalf("findBackups failed: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("Expected only the original hook to be backed up, got %v", backups)
+	}
+
+	if err := hm.UninstallHooks(); err != nil {
+		t.Fatalf("UninstallHooks failed: %v", err)
+	}
+	if err := hm.RestoreHooks(0); err != nil {
+		t.Fatalf("RestoreHooks failed: %v", err)
+	}
+	if content := readFile(t, tmpDir, ".git/hooks/pre-commit"); content != original {
+		t.Errorf("Expected the original hook to be restored, got %q", content)
+	}
+}
@@ -64,4 +64,4 @@ echo ""
 echo "📝 To install, users should:"
 echo "  1. Download the appropriate archive for their platform"
 echo "  2. Extract it to a directory in their PATH"
-echo "  3. Run 'hooky --install' in their git repository"
\ No newline at end of file
+echo "  3. Run 'hooky install' in their git repository"
\ No newline at end of file
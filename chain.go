@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// chainedHookPath returns where an existing hook kept by the "chain"
+// preserve_existing mode is stored.
+func (hm *HookManager) chainedHookPath(hookName string) string {
+	return filepath.Join(hm.gitDir, hm.config.Settings.BackupDirectory, hookName+".chained")
+}
+
+// chainHook moves the existing hook at hookPath to the chained hook location,
+// so hooky keeps running it. A hook chained by an earlier install is moved to
+// a regular backup first.
+func (hm *HookManager) chainHook(hookName, hookPath string) error {
+	chainedPath := hm.chainedHookPath(hookName)
+	if err := os.MkdirAll(filepath.Dir(chainedPath), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	if _, err := os.Stat(chainedPath); err == nil {
+		backupPath := filepath.Join(filepath.Dir(chainedPath), fmt.Sprintf("%s.%d", hookName, time.Now().Unix()))
+		if err := os.Rename(chainedPath, backupPath); err != nil {
+			return fmt.Errorf("failed to backup previously chained hook: %w", err)
+		}
+		fmt.Printf("  Replaced previously chained %s hook, backed up to: %s\n", hookName, backupPath)
+	}
+
+	if err := os.Rename(hookPath, chainedPath); err != nil {
+		return fmt.Errorf("failed to chain existing hook: %w", err)
+	}
+
+	if hm.config.Settings.Verbose {
+		fmt.Printf("  Existing hook will run %s the configured steps: %s\n", hm.config.Settings.ChainOrder, chainedPath)
+	}
+	return nil
+}
+
+// unchainHook moves a chained hook back to hookPath. It does nothing if
+// hookName has no chained hook.
+func (hm *HookManager) unchainHook(hookName, hookPath string) error {
+	chainedPath := hm.chainedHookPath(hookName)
+	if _, err := os.Stat(chainedPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	if err := os.Rename(chainedPath, hookPath); err != nil {
+		return fmt.Errorf("failed to restore chained hook %s: %w", hookName, err)
+	}
+
+	if hm.config.Settings.Verbose {
+		fmt.Printf("Restored chained hook: %s\n", hookName)
+	}
+	return nil
+}
+
+// withChainedHook returns scripts with the chained hook for hookName added
+// as a step before or after them, depending on the chain_order setting. The
+// chained hook receives the hook's arguments like any other step.
+func (hm *HookManager) withChainedHook(hookName string, scripts []HookScript) []HookScript {
+	if hm.config.Settings.PreserveExisting != "chain" {
+		return scripts
+	}
+
+	chainedPath := hm.chainedHookPath(hookName)
+	if _, err := os.Stat(chainedPath); err != nil {
+		return scripts
+	}
+
+	chained := HookScript{
+		Name:        fmt.Sprintf("existing %s hook", hookName),
+		Script:      shellQuote(filepath.ToSlash(chainedPath)),
+		Description: "Hook that was installed before hooky",
+	}
+
+	if hm.config.Settings.ChainOrder == "after" {
+		return append(append([]HookScript{}, scripts...), chained)
+	}
+	return append([]HookScript{chained}, scripts...)
+}
+
+// shellQuote quotes s as a single sh word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withStdin makes content the process's standard input for the rest of the
+// test.
+func withStdin(t *testing.T, content string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write stdin: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open stdin: %v", err)
+	}
+
+	oldStdin := os.Stdin
+	os.Stdin = f
+	t.Cleanup(func() {
+		os.Stdin = oldStdin
+		f.Close()
+	})
+}
+
+func TestChainExistingHook(t *testing.T) {
+	tests := []struct {
+		name          string
+		order         string
+		chainedExit   string
+		expectedLog   string
+		expectFailure bool
+	}{
+		{
+			name:        "chained hook runs before steps",
+			order:       "before",
+			expectedLog: "chained origin refs\nstep origin refs\n",
+		},
+		{
+			name:        "chained hook runs after steps",
+			order:       "after",
+			expectedLog: "step origin refs\nchained origin refs\n",
+		},
+		{
+			name:          "failing chained hook fails the hook",
+			order:         "before",
+			chainedExit:   "exit 1",
+			expectedLog:   "chained origin refs\n",
+			expectFailure: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := setupRunRepo(t, `
+hooks:
+  pre-push:
+    - name: "step"
+      command: "echo step $1 $(cat) >> log; true"
+      description: "Reads the pushed refs"
+settings:
+  preserve_existing: chain
+  chain_order: `+tt.order+`
+`)
+
+			original := "#!/bin/sh\necho chained $1 $(cat) >> log\n" + tt.chainedExit + "\n"
+			writeFile(t, tmpDir, ".git/hooks/pre-push", original)
+			if err := os.Chmod(filepath.Join(tmpDir, ".git/hooks/pre-push"), 0755); err != nil {
+				t.Fatalf("Failed to make hook executable: %v", err)
+			}
+
+			if err := NewHookManager("hooky.yaml", false).InstallHooks(); err != nil {
+				t.Fatalf("InstallHooks failed: %v", err)
+			}
+
+			if content := readFile(t, tmpDir, ".git/.hooky-backup/pre-push.chained"); content != original {
+				t.Fatalf("Expected the existing hook to be chained, got %q", content)
+			}
+
+			withStdin(t, "refs")
+			err := NewHookManager("hooky.yaml", false).RunHook("pre-push", []string{"origin", "url"})
+
+			var hookErr *HookError
+			if tt.expectFailure {
+				if !errors.As(err, &hookErr) || hookErr.Failed[0].Step != "existing pre-push hook" {
+					t.Errorf("Expected the chained hook to fail the hook, got: %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("RunHook failed: %v", err)
+			}
+
+			if log := readFile(t, tmpDir, "log"); log != tt.expectedLog {
+				t.Errorf("Expected log %q, got %q", tt.expectedLog, log)
+			}
+		})
+	}
+}
+
+func TestUninstallRestoresChainedHook(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "echo"
+      command: "echo test"
+      description: "Echo"
+settings:
+  preserve_existing: chain
+`)
+
+	original := "#!/bin/sh\necho original\n"
+	writeFile(t, tmpDir, ".git/hooks/pre-commit", original)
+
+	if err := NewHookManager("hooky.yaml", false).InstallHooks(); err != nil {
+		t.Fatalf("InstallHooks failed: %v", err)
+	}
+
+	// Reinstalling must not chain hooky's own hook
+	if err := NewHookManager("hooky.yaml", false).InstallHooks(); err != nil {
+		t.Fatalf("InstallHooks failed: %v", err)
+	}
+	if content := readFile(t, tmpDir, ".git/.hooky-backup/pre-commit.chained"); content != original {
+		t.Fatalf("Expected the original hook to stay chained, got %q", content)
+	}
+
+	if err := NewHookManager("hooky.yaml", false).UninstallHooks(); err != nil {
+		t.Fatalf("UninstallHooks failed: %v", err)
+	}
+
+	if content := readFile(t, tmpDir, ".git/hooks/pre-commit"); content != original {
+		t.Errorf("Expected the chained hook to be put back, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, ".git/.hooky-backup/pre-commit.chained")); !os.IsNotExist(err) {
+		t.Error("Chained hook should be removed from the backup directory")
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"/repo/.git/hook": `'/repo/.git/hook'`,
+		"/my repo/hook":   `'/my repo/hook'`,
+		"/it's/hook":      `'/it'\''s/hook'`,
+	}
+
+	for input, expected := range tests {
+		if got := shellQuote(input); got != expected {
+			t.Errorf("shellQuote(%q) = %s, expected %s", input, got, expected)
+		}
+	}
+}
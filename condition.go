@@ -0,0 +1,568 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// conditionContext is what a step's if: expression is evaluated against.
+type conditionContext struct {
+	Hook   string
+	Branch string
+	Remote string
+	OS     string
+
+	// Files are the hook's changed files, when it has such a list, and
+	// Updates the refs being pushed, when known.
+	Files      []string
+	HasFiles   bool
+	Updates    []refUpdate
+	HasUpdates bool
+
+	// LookupEnv looks up env.NAME; withVars adds a step's own variables.
+	LookupEnv func(name string) (string, bool)
+}
+
+// withVars returns a copy of c in which env.NAME sees vars on top of c's
+// environment, as a step with those variables does.
+func (c *conditionContext) withVars(vars map[string]string) *conditionContext {
+	stepContext := *c
+	stepContext.LookupEnv = func(name string) (string, bool) {
+		if value, ok := vars[name]; ok {
+			return value, true
+		}
+		return c.LookupEnv(name)
+	}
+	return &stepContext
+}
+
+// newConditionContext returns the context for hookName with the current
+// branch, OS and environment filled in.
+func newConditionContext(hookName string) *conditionContext {
+	return &conditionContext{
+		Hook:      hookName,
+		Branch:    currentBranch(),
+		OS:        runtime.GOOS,
+		LookupEnv: os.LookupEnv,
+	}
+}
+
+// listConditionContext returns the context hooky list evaluates if:
+// expressions in: the current branch and environment and the files the
+// hook would see now, without a remote or pushed refs. hooky list does not
+// give a verdict for expressions that depend on the push (see
+// dependsOnPush).
+func listConditionContext(hookName string) *conditionContext {
+	c := newConditionContext(hookName)
+	if files, ok, err := changedFiles(hookName, nil, nil); err == nil {
+		c.Files, c.HasFiles = files, ok
+	}
+	return c
+}
+
+// currentBranch returns the short name of the checked-out branch, or an
+// empty string on a detached HEAD.
+func currentBranch() string {
+	branch, err := runGit("", "symbolic-ref", "--short", "-q", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(branch)
+}
+
+// evalCondition evaluates script's if: expression in c, returning whether
+// the step runs and why.
+func evalCondition(script HookScript, c *conditionContext) (bool, string) {
+	cond, err := parseCondition(script.If)
+	if err != nil {
+		return false, fmt.Sprintf("invalid if: %v", err)
+	}
+	return cond.eval(c)
+}
+
+// callsFunction reports whether the if: expression expr calls the function
+// name, such as changed(), which needs the hook's changed files.
+func callsFunction(expr, name string) bool {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return false
+	}
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].kind == tokenIdent && tokens[i].text == name && tokens[i+1].kind == tokenOp && tokens[i+1].text == "(" {
+			return true
+		}
+	}
+	return false
+}
+
+// dependsOnPush reports whether the if: expression expr reads what only a
+// push in progress knows: the remote or the pushed refs.
+func dependsOnPush(expr string) bool {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return false
+	}
+	for i, tok := range tokens {
+		if tok.kind != tokenIdent {
+			continue
+		}
+		call := i+1 < len(tokens) && tokens[i+1].kind == tokenOp && tokens[i+1].text == "("
+		if (tok.text == "pushed" && call) || (tok.text == "remote" && !call) {
+			return true
+		}
+	}
+	return false
+}
+
+// condition is a parsed if: expression. Evaluating it also returns the
+// reason for the result, naming the values that decided it.
+type condition interface {
+	eval(c *conditionContext) (bool, string)
+}
+
+// operand is a string value in a condition: a variable or a literal.
+type operand interface {
+	value(c *conditionContext) string
+	// describe explains the operand's value, or returns "" for literals
+	describe(c *conditionContext) string
+}
+
+type literal string
+
+func (l literal) value(*conditionContext) string    { return string(l) }
+func (l literal) describe(*conditionContext) string { return "" }
+
+// variable is one of the built-in variables: branch, remote, os or hook.
+type variable string
+
+func (v variable) value(c *conditionContext) string {
+	switch v {
+	case "branch":
+		return c.Branch
+	case "remote":
+		return c.Remote
+	case "os":
+		return c.OS
+	default:
+		return c.Hook
+	}
+}
+
+func (v variable) describe(c *conditionContext) string {
+	return fmt.Sprintf("%s is %q", v, v.value(c))
+}
+
+// envVar is env.NAME, the value of an environment variable.
+type envVar string
+
+func (e envVar) value(c *conditionContext) string {
+	value, _ := c.LookupEnv(string(e))
+	return value
+}
+
+func (e envVar) describe(c *conditionContext) string {
+	if value, ok := c.LookupEnv(string(e)); ok {
+		return fmt.Sprintf("env.%s is %q", string(e), value)
+	}
+	return fmt.Sprintf("env.%s is not set", string(e))
+}
+
+// truth is an operand used as a condition, true when it is not empty.
+type truth struct{ operand operand }
+
+func (t truth) eval(c *conditionContext) (bool, string) {
+	return t.operand.value(c) != "", t.operand.describe(c)
+}
+
+type comparison struct {
+	left, right operand
+	equal       bool
+}
+
+func (cmp comparison) eval(c *conditionContext) (bool, string) {
+	result := (cmp.left.value(c) == cmp.right.value(c)) == cmp.equal
+
+	var reasons []string
+	for _, operand := range []operand{cmp.left, cmp.right} {
+		if reason := operand.describe(c); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	if len(reasons) == 0 {
+		op := "!="
+		if cmp.equal {
+			op = "=="
+		}
+		reasons = append(reasons, fmt.Sprintf("%q %s %q", cmp.left.value(c), op, cmp.right.value(c)))
+	}
+	return result, strings.Join(reasons, ", ")
+}
+
+type not struct{ operand condition }
+
+func (n not) eval(c *conditionContext) (bool, string) {
+	result, reason := n.operand.eval(c)
+	return !result, reason
+}
+
+// logical is && (and) or || (or). Operands are evaluated from left to right
+// until the result is known; the reason is that of the deciding operand, or
+// of all operands when each one counted.
+type logical struct {
+	left, right condition
+	and         bool
+}
+
+func (l logical) eval(c *conditionContext) (bool, string) {
+	left, leftReason := l.left.eval(c)
+	if left != l.and {
+		return left, leftReason
+	}
+	right, rightReason := l.right.eval(c)
+	if right != l.and {
+		return right, rightReason
+	}
+	return right, joinReasons(leftReason, rightReason)
+}
+
+func joinReasons(reasons ...string) string {
+	var nonEmpty []string
+	for _, reason := range reasons {
+		if reason != "" {
+			nonEmpty = append(nonEmpty, reason)
+		}
+	}
+	return strings.Join(nonEmpty, ", ")
+}
+
+// changed is changed("glob", ...), true when a changed file matches one of
+// the patterns.
+type changed struct{ patterns Patterns }
+
+func (ch changed) eval(c *conditionContext) (bool, string) {
+	if !c.HasFiles {
+		return false, fmt.Sprintf("%s has no changed files", c.Hook)
+	}
+	for _, file := range c.Files {
+		if matchAny(ch.patterns, file) {
+			return true, fmt.Sprintf("%s changed", file)
+		}
+	}
+	return false, fmt.Sprintf("no changed file matches %s", quoteAll(ch.patterns))
+}
+
+// pushed is pushed("ref pattern", ...), true when a pushed remote ref
+// matches one of the patterns, like only_refs. When the refs are unknown, as
+// when pre-push is run by hand, it is false, so !pushed(...) is true.
+type pushed struct{ patterns Patterns }
+
+func (p pushed) eval(c *conditionContext) (bool, string) {
+	if !c.HasUpdates {
+		return false, "the pushed refs are unknown"
+	}
+	for _, update := range c.Updates {
+		if matchAny(p.patterns, update.RemoteRef) {
+			return true, fmt.Sprintf("pushing %s", update.RemoteRef)
+		}
+	}
+	return false, fmt.Sprintf("no pushed ref matches %s", quoteAll(p.patterns))
+}
+
+// matches is matches(value, "glob", ...), true when the value matches one of
+// the patterns, with the same rules as files.
+type matches struct {
+	operand  operand
+	patterns Patterns
+}
+
+func (m matches) eval(c *conditionContext) (bool, string) {
+	value := m.operand.value(c)
+	reason := m.operand.describe(c)
+	if reason == "" {
+		reason = strconv.Quote(value)
+	}
+	return value != "" && matchAny(m.patterns, value), reason
+}
+
+func quoteAll(patterns Patterns) string {
+	quoted := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		quoted[i] = strconv.Quote(pattern)
+	}
+	return strings.Join(quoted, " or ")
+}
+
+// parseCondition parses an if: expression:
+//
+//	expr    = or
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | primary
+//	primary = "(" expr ")" | call | operand [ ("==" | "!=") operand ]
+//	operand = "branch" | "remote" | "os" | "hook" | "env." NAME | string
+//	call    = ("changed" | "pushed") "(" string { "," string } ")"
+//	        | "matches" "(" operand "," string { "," string } ")"
+//
+// Strings are quoted with double or single quotes.
+func parseCondition(expr string) (condition, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &conditionParser{tokens: tokens}
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEnd {
+		return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+	}
+	return cond, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEnd:
+		return "end of expression"
+	default:
+		return strconv.Quote(t.text)
+	}
+}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			var text strings.Builder
+			j := i + 1
+			for ; j < len(expr) && expr[j] != c; j++ {
+				if expr[j] == '\\' && j+1 < len(expr) {
+					j++
+				}
+				text.WriteByte(expr[j])
+			}
+			if j == len(expr) {
+				return nil, fmt.Errorf("unterminated string at position %d", i+1)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text.String(), pos: i + 1})
+			i = j + 1
+		case isAlpha(c) || c == '_':
+			j := i
+			for j < len(expr) && (isAlpha(expr[j]) || expr[j] == '_' || expr[j] == '.' || (expr[j] >= '0' && expr[j] <= '9')) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: expr[i:j], pos: i + 1})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "&&", "||", "!", "(", ")", ","} {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i+1)
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: i + 1})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, pos: len(expr) + 1}), nil
+}
+
+type conditionParser struct {
+	tokens []token
+	next   int
+}
+
+func (p *conditionParser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *conditionParser) take() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEnd {
+		p.next++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is the operator op.
+func (p *conditionParser) accept(op string) bool {
+	if tok := p.peek(); tok.kind == tokenOp && tok.text == op {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *conditionParser) expect(op string) error {
+	if !p.accept(op) {
+		tok := p.peek()
+		return fmt.Errorf("expected %q, got %s at position %d", op, tok, tok.pos)
+	}
+	return nil
+}
+
+func (p *conditionParser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (condition, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{left: left, right: right, and: true}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseUnary() (condition, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return not{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *conditionParser) parsePrimary() (condition, error) {
+	if p.accept("(") {
+		cond, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return cond, p.expect(")")
+	}
+
+	if tok := p.peek(); tok.kind == tokenIdent && p.tokens[p.next+1].text == "(" {
+		return p.parseCall()
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!="} {
+		if p.accept(op) {
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return comparison{left: left, right: right, equal: op == "=="}, nil
+		}
+	}
+	return truth{operand: left}, nil
+}
+
+func (p *conditionParser) parseOperand() (operand, error) {
+	tok := p.take()
+	switch tok.kind {
+	case tokenString:
+		return literal(tok.text), nil
+	case tokenIdent:
+		switch tok.text {
+		case "branch", "remote", "os", "hook":
+			return variable(tok.text), nil
+		}
+		if name, ok := strings.CutPrefix(tok.text, "env."); ok {
+			if !isVarName(name) {
+				return nil, fmt.Errorf("invalid variable name %q at position %d", name, tok.pos)
+			}
+			return envVar(name), nil
+		}
+		return nil, fmt.Errorf("unknown variable %q at position %d", tok.text, tok.pos)
+	default:
+		return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+	}
+}
+
+func (p *conditionParser) parseCall() (condition, error) {
+	name := p.take()
+	p.take() // "("
+	switch name.text {
+	case "changed", "pushed", "matches":
+	default:
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.pos)
+	}
+
+	var operand operand
+	if name.text == "matches" {
+		var err error
+		if operand, err = p.parseOperand(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+
+	var patterns Patterns
+	for {
+		tok := p.take()
+		if tok.kind != tokenString {
+			return nil, fmt.Errorf("%s: expected a quoted pattern, got %s at position %d", name.text, tok, tok.pos)
+		}
+		if err := validateGlob(tok.text); err != nil {
+			return nil, fmt.Errorf("%s: %w", name.text, err)
+		}
+		patterns = append(patterns, tok.text)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	switch name.text {
+	case "changed":
+		return changed{patterns: patterns}, nil
+	case "pushed":
+		return pushed{patterns: patterns}, nil
+	default:
+		return matches{operand: operand, patterns: patterns}, nil
+	}
+}
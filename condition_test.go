@@ -0,0 +1,172 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseConditionErrors(t *testing.T) {
+	tests := []struct {
+		expr     string
+		errorMsg string
+	}{
+		{expr: `branch = "main"`, errorMsg: `unexpected '=' at position 8`},
+		{expr: `branch == "main`, errorMsg: "unterminated string at position 11"},
+		{expr: `tag == "v1"`, errorMsg: `unknown variable "tag"`},
+		{expr: `env.1X`, errorMsg: `invalid variable name "1X"`},
+		{expr: `exists("go.mod")`, errorMsg: `unknown function "exists"`},
+		{expr: `changed(go)`, errorMsg: "changed: expected a quoted pattern"},
+		{expr: `changed("[")`, errorMsg: "invalid file pattern"},
+		{expr: `matches("x")`, errorMsg: `expected ","`},
+		{expr: `(branch == "main"`, errorMsg: `expected ")", got end of expression`},
+		{expr: `branch == "main" os`, errorMsg: `unexpected "os" at position 18`},
+		{expr: ``, errorMsg: "unexpected end of expression"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := parseCondition(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Expected error containing %q, got: %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestEvalCondition(t *testing.T) {
+	env := map[string]string{"CI": "true", "EMPTY": ""}
+	c := &conditionContext{
+		Hook:       "pre-push",
+		Branch:     "release/1.2",
+		Remote:     "origin",
+		OS:         "linux",
+		Files:      []string{"app/main.go", "README.md"},
+		HasFiles:   true,
+		Updates:    []refUpdate{{LocalRef: "refs/heads/release/1.2", RemoteRef: "refs/heads/main"}},
+		HasUpdates: true,
+		LookupEnv: func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		},
+	}
+
+	tests := []struct {
+		expr     string
+		expected bool
+		reason   string
+	}{
+		{expr: `branch == "main"`, expected: false, reason: `branch is "release/1.2"`},
+		{expr: `branch != 'main'`, expected: true, reason: `branch is "release/1.2"`},
+		{expr: `matches(branch, "release/*")`, expected: true, reason: `branch is "release/1.2"`},
+		{expr: `remote == "origin" && os == "linux"`, expected: true, reason: `remote is "origin", os is "linux"`},
+		{expr: `os == "windows" && remote == "origin"`, expected: false, reason: `os is "linux"`},
+		{expr: `os == "windows" || hook == "pre-push"`, expected: true, reason: `hook is "pre-push"`},
+		{expr: `env.CI`, expected: true, reason: `env.CI is "true"`},
+		{expr: `!env.CI`, expected: false, reason: `env.CI is "true"`},
+		{expr: `!env.EMPTY && !env.UNSET`, expected: true, reason: `env.EMPTY is "", env.UNSET is not set`},
+		{expr: `env.CI == "true"`, expected: true, reason: `env.CI is "true"`},
+		{expr: `"a" == "a"`, expected: true, reason: `"a" == "a"`},
+		{expr: `changed("*.go")`, expected: true, reason: "app/main.go changed"},
+		{expr: `changed("docs/**", "*.txt")`, expected: false, reason: `no changed file matches "docs/**" or "*.txt"`},
+		{expr: `pushed("refs/heads/main")`, expected: true, reason: "pushing refs/heads/main"},
+		{expr: `pushed("refs/tags/*")`, expected: false, reason: `no pushed ref matches "refs/tags/*"`},
+		{expr: `!(branch == "main" || env.CI) && changed("*.md")`, expected: false, reason: `env.CI is "true"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			result, reason := evalCondition(HookScript{If: tt.expr}, c)
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+			if reason != tt.reason {
+				t.Errorf("Expected reason %q, got %q", tt.reason, reason)
+			}
+		})
+	}
+}
+
+func TestDependsOnPush(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{expr: `remote == "origin"`, expected: true},
+		{expr: `!pushed("refs/heads/main")`, expected: true},
+		{expr: `os == "linux" || matches(remote, "up*")`, expected: true},
+		{expr: `branch == "remote" && env.remote`, expected: false},
+		{expr: `changed("pushed")`, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := dependsOnPush(tt.expr); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPushedWithoutRefs(t *testing.T) {
+	c := &conditionContext{Hook: "pre-push"}
+
+	if result, reason := evalCondition(HookScript{If: `pushed("refs/heads/main")`}, c); result || reason != "the pushed refs are unknown" {
+		t.Errorf("Expected pushed() to be false without refs, got %v (%s)", result, reason)
+	}
+	if result, _ := evalCondition(HookScript{If: `!pushed("refs/heads/main")`}, c); !result {
+		t.Error("Expected !pushed() to be true without refs")
+	}
+}
+
+func TestConditionalSteps(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "go"
+      command: "echo go >> log; true"
+      description: "Only when Go files change"
+      if: 'changed("*.go")'
+    - name: "docs"
+      command: "echo docs >> log; true"
+      description: "Only when Markdown files change"
+      if: 'changed("*.md")'
+    - name: "local"
+      command: "echo local >> log; true"
+      description: "Only outside CI"
+      if: "!env.HOOKY_TEST_CI"
+    - name: "branch"
+      command: "echo branch >> log; true"
+      description: "Only on the feature branch"
+      if: 'branch == "feature"'
+    - name: "step-env"
+      command: "echo step-env >> log; true"
+      description: "Sees its own env"
+      if: 'env.COND_TEST_MODE == "fast" && env.COND_TEST_SHARED'
+      env:
+        COND_TEST_MODE: fast
+settings:
+  env:
+    COND_TEST_SHARED: "1"
+`)
+	gitRun(t, tmpDir, "checkout", "-b", "feature")
+	writeFiles(t, tmpDir, "main.go")
+	gitRun(t, tmpDir, "add", "main.go")
+
+	if err := NewHookManager("hooky.yaml", false).RunHook("pre-commit", nil); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+	if log := readFile(t, tmpDir, "log"); log != "go\nlocal\nbranch\nstep-env\n" {
+		t.Errorf("Unexpected steps run: %q", log)
+	}
+
+	os.Remove(filepath.Join(tmpDir, "log"))
+	t.Setenv("HOOKY_TEST_CI", "1")
+	if err := NewHookManager("hooky.yaml", false).RunHook("pre-commit", nil); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+	if log := readFile(t, tmpDir, "log"); log != "go\nbranch\nstep-env\n" {
+		t.Errorf("Unexpected steps run in CI: %q", log)
+	}
+}
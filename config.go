@@ -2,16 +2,103 @@ package main
 
 import (
 	"fmt"
-	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type HookScript struct {
-	Name        string `yaml:"name"`
-	Script      string `yaml:"script,omitempty"`
-	Command     string `yaml:"command,omitempty"`
-	Description string `yaml:"description"`
+	Name          string   `yaml:"name"`
+	Script        string   `yaml:"script,omitempty"`
+	Command       string   `yaml:"command,omitempty"`
+	Description   string   `yaml:"description"`
+	Parallel      bool     `yaml:"parallel,omitempty"`
+	Timeout       string   `yaml:"timeout,omitempty"`
+	Files         Patterns `yaml:"files,omitempty"`
+	Exclude       Patterns `yaml:"exclude,omitempty"`
+	PassFilenames bool     `yaml:"pass_filenames,omitempty"`
+	Fixer         bool     `yaml:"fixer,omitempty"`
+	Restage       bool     `yaml:"restage,omitempty"`
+	WorkingDir    string   `yaml:"working_dir,omitempty"`
+
+	// If is an expression deciding whether the step runs, such as
+	// branch == "main" && !env.CI (see parseCondition).
+	If string `yaml:"if,omitempty"`
+
+	// OnlyRefs limits a pre-push step to pushes updating a remote ref that
+	// matches one of the patterns, such as refs/heads/main.
+	OnlyRefs Patterns `yaml:"only_refs,omitempty"`
+
+	// FileList asks for HOOKY_PUSHED_FILES in a pre-push step that does not
+	// otherwise use the pushed files, which are only looked up when a step
+	// needs them.
+	FileList bool `yaml:"file_list,omitempty"`
+
+	// Env sets variables for the step, on top of those from EnvFile, a
+	// dotenv-style file relative to the step's working directory.
+	Env     map[string]string `yaml:"env,omitempty"`
+	EnvFile string            `yaml:"env_file,omitempty"`
+
+	// Disabled turns off a step defined in an earlier file, such as the
+	// shared configuration overridden by hooky.local.yaml.
+	Disabled bool `yaml:"disabled,omitempty"`
+
+	// Source is the configuration file the step was loaded from.
+	Source string `yaml:"-"`
+
+	// Raw is the script or command line as written, when expanding
+	// variables in it changed it.
+	Raw string `yaml:"-"`
+
+	// Local marks steps added by the local override file, and Overridden
+	// lists the fields it set on a shared step.
+	Local      bool     `yaml:"-"`
+	Overridden []string `yaml:"-"`
+}
+
+// Patterns is a list of glob patterns. In YAML it may be written as a single
+// string or as a list of strings.
+type Patterns []string
+
+func (p *Patterns) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*p = Patterns{value.Value}
+		return nil
+	}
+
+	var patterns []string
+	if err := value.Decode(&patterns); err != nil {
+		return err
+	}
+	*p = patterns
+	return nil
+}
+
+// usesFiles reports whether the step depends on the hook's changed files,
+// to decide whether to run, to receive them as arguments or to check them
+// for modifications.
+func (s HookScript) usesFiles() bool {
+	return len(s.Files) > 0 || len(s.Exclude) > 0 || s.PassFilenames || s.Fixer || s.WorkingDir != ""
+}
+
+// Dir returns the directory the step runs in, relative to the repository
+// root.
+func (s HookScript) Dir() string {
+	if s.WorkingDir == "" {
+		return "."
+	}
+	return filepath.FromSlash(s.WorkingDir)
+}
+
+// CommandLine returns the shell line executed for this step.
+func (s HookScript) CommandLine() string {
+	if s.Script != "" {
+		return s.Script
+	}
+	return s.Command
 }
 
 type Settings struct {
@@ -19,39 +106,141 @@ type Settings struct {
 	BackupExisting  bool   `yaml:"backup_existing"`
 	BackupDirectory string `yaml:"backup_directory"`
 	Verbose         bool   `yaml:"verbose"`
+	ParallelWorkers int    `yaml:"parallel_workers"`
+	Timeout         string `yaml:"timeout"`
+	StashUnstaged   bool   `yaml:"stash_unstaged"`
+
+	// PreserveExisting decides what happens to an existing hook that hooky
+	// replaces: "backup" moves it to the backup directory, "chain" also keeps
+	// running it before or after the steps, as set by ChainOrder.
+	PreserveExisting string `yaml:"preserve_existing"`
+	ChainOrder       string `yaml:"chain_order"`
+
+	// HooksPath, relative to the repository root, makes install generate
+	// hooks into a tracked directory and point core.hooksPath at it.
+	HooksPath string `yaml:"hooks_path"`
+
+	// NestedConfigs merges the hooks of configuration files with the same
+	// name found in subdirectories of the repository.
+	NestedConfigs bool `yaml:"nested_configs"`
+
+	// Lockfile decides what run does when script files or shared
+	// configuration differ from hooky.lock: "warn", "strict" to refuse to run,
+	// or "off" to neither check nor write the lock file.
+	Lockfile string `yaml:"lockfile"`
+
+	// StrictVariables makes an unset ${VAR} without a default an error
+	// instead of expanding to nothing.
+	StrictVariables bool `yaml:"strict_variables"`
+
+	// Env and EnvFile set variables for every step, which a step's own env
+	// and env_file override. EnvFile is relative to the repository root.
+	Env     map[string]string `yaml:"env"`
+	EnvFile string            `yaml:"env_file"`
 }
 
 type Config struct {
 	Hooks    map[string][]HookScript `yaml:"hooks"`
 	Settings Settings              `yaml:"settings"`
+
+	// Files lists the configuration files the hooks were loaded from.
+	Files []string `yaml:"-"`
+
+	// Resolved maps each source fetched from a git repository, as
+	// "repo@ref", to the commit it resolved to.
+	Resolved map[string]string `yaml:"-"`
+
+	// Disabled holds the steps turned off with disabled: true, per hook.
+	Disabled map[string][]HookScript `yaml:"-"`
+
+	// RawSettings holds the settings values, as written, that contained
+	// variables.
+	RawSettings map[string]string `yaml:"-"`
 }
 
 func LoadConfig(configPath string) (*Config, error) {
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+	return loadConfig(configPath, false)
+}
+
+// loadConfig reads configPath and the files it includes, then its local
+// override file, and expands variables in the result. With refresh, files
+// included from git repositories are fetched again instead of being read
+// from the cache.
+func loadConfig(configPath string, refresh bool) (*Config, error) {
+	config := &Config{
+		// Set defaults
+		Settings: Settings{
+			AutoExecutable:   true,
+			BackupExisting:   true,
+			BackupDirectory:  ".hooky-backup",
+			Verbose:          false,
+			PreserveExisting: "backup",
+			ChainOrder:       "before",
+			Lockfile:         "warn",
+		},
 	}
 
-	var config Config
-	
-	// Set defaults
-	config.Settings = Settings{
-		AutoExecutable:  true,
-		BackupExisting:  true,
-		BackupDirectory: ".hooky-backup",
-		Verbose:         false,
+	loader := &configLoader{config: config, refresh: refresh}
+	if err := loader.load(configSource{Path: configPath}); err != nil {
+		return nil, err
 	}
 
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	if err := applyLocalConfig(config, localConfigPath(configPath)); err != nil {
+		return nil, err
 	}
 
-	// Validate hook script configuration
-	if err := validateHookScripts(config.Hooks); err != nil {
+	// Set disabled steps aside so that nothing installs, validates or runs them
+	for hookName, scripts := range config.Hooks {
+		var enabled []HookScript
+		for _, script := range scripts {
+			if !script.Disabled {
+				enabled = append(enabled, script)
+				continue
+			}
+			if config.Disabled == nil {
+				config.Disabled = make(map[string][]HookScript)
+			}
+			config.Disabled[hookName] = append(config.Disabled[hookName], script)
+		}
+		config.Hooks[hookName] = enabled
+	}
+
+	if err := expandSteps(config); err != nil {
 		return nil, err
 	}
 
-	return &config, nil
+	return config, nil
+}
+
+// validateSettings checks settings values that the YAML types do not.
+func validateSettings(settings Settings) error {
+	if settings.ParallelWorkers < 0 {
+		return fmt.Errorf("settings.parallel_workers must not be negative")
+	}
+
+	if _, err := parseTimeout(settings.Timeout); err != nil {
+		return fmt.Errorf("settings.timeout: %w", err)
+	}
+
+	switch settings.PreserveExisting {
+	case "backup", "chain":
+	default:
+		return fmt.Errorf("settings.preserve_existing must be \"backup\" or \"chain\", got %q", settings.PreserveExisting)
+	}
+
+	switch settings.ChainOrder {
+	case "before", "after":
+	default:
+		return fmt.Errorf("settings.chain_order must be \"before\" or \"after\", got %q", settings.ChainOrder)
+	}
+
+	switch settings.Lockfile {
+	case "warn", "strict", "off":
+	default:
+		return fmt.Errorf("settings.lockfile must be \"warn\", \"strict\" or \"off\", got %q", settings.Lockfile)
+	}
+
+	return nil
 }
 
 func validateHookScripts(hooks map[string][]HookScript) error {
@@ -67,11 +256,86 @@ func validateHookScripts(hooks map[string][]HookScript) error {
 			if hasScript && hasCommand {
 				return fmt.Errorf("hook %s[%d] (%s): cannot specify both 'script' and 'command', use only one", hookName, i, script.Name)
 			}
+
+			if _, err := parseTimeout(script.Timeout); err != nil {
+				return fmt.Errorf("hook %s[%d] (%s): %w", hookName, i, script.Name, err)
+			}
+
+			if script.PassFilenames && !hasChangedFiles(hookName) {
+				return fmt.Errorf("hook %s[%d] (%s): pass_filenames is only supported for pre-commit and pre-push", hookName, i, script.Name)
+			}
+
+			if script.Fixer && hookName != "pre-commit" {
+				return fmt.Errorf("hook %s[%d] (%s): fixer is only supported for pre-commit", hookName, i, script.Name)
+			}
+
+			if script.Fixer && script.Parallel {
+				return fmt.Errorf("hook %s[%d] (%s): fixer steps cannot run in parallel", hookName, i, script.Name)
+			}
+
+			if script.If != "" {
+				if _, err := parseCondition(script.If); err != nil {
+					return fmt.Errorf("hook %s[%d] (%s): invalid if: %w", hookName, i, script.Name, err)
+				}
+			}
+
+			if len(script.OnlyRefs) > 0 && hookName != "pre-push" {
+				return fmt.Errorf("hook %s[%d] (%s): only_refs is only supported for pre-push", hookName, i, script.Name)
+			}
+			if script.FileList && hookName != "pre-push" {
+				return fmt.Errorf("hook %s[%d] (%s): file_list is only supported for pre-push", hookName, i, script.Name)
+			}
+
+			if script.Restage && !script.Fixer {
+				return fmt.Errorf("hook %s[%d] (%s): restage requires fixer: true", hookName, i, script.Name)
+			}
+
+			if script.WorkingDir != "" {
+				if err := validateWorkingDir(script.WorkingDir); err != nil {
+					return fmt.Errorf("hook %s[%d] (%s): %w", hookName, i, script.Name, err)
+				}
+			}
+
+			for _, pattern := range append(append(append([]string{}, script.Files...), script.Exclude...), script.OnlyRefs...) {
+				if err := validateGlob(pattern); err != nil {
+					return fmt.Errorf("hook %s[%d] (%s): %w", hookName, i, script.Name, err)
+				}
+			}
 		}
 	}
 	return nil
 }
 
+// validateWorkingDir checks that a step's working_dir stays inside the
+// repository.
+func validateWorkingDir(dir string) error {
+	if filepath.IsAbs(dir) || path.IsAbs(filepath.ToSlash(dir)) {
+		return fmt.Errorf("working_dir %q must be relative to the repository root", dir)
+	}
+	clean := path.Clean(filepath.ToSlash(dir))
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("working_dir %q must be inside the repository", dir)
+	}
+	return nil
+}
+
+// parseTimeout parses a step or default timeout such as "30s" or "5m".
+// An empty value means no timeout.
+func parseTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", value, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must be positive", value)
+	}
+	return timeout, nil
+}
+
 // GetSupportedHooks returns all git hooks that can be managed
 func GetSupportedHooks() []string {
 	return []string{
@@ -94,4 +358,13 @@ func GetSupportedHooks() []string {
 		"pre-push",
 		"push-to-checkout",
 	}
-}
\ No newline at end of file
+}
+
+func isSupportedHook(hookName string) bool {
+	for _, hook := range GetSupportedHooks() {
+		if hook == hookName {
+			return true
+		}
+	}
+	return false
+}
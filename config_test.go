@@ -75,6 +75,267 @@ hooks:
 			expectError: true,
 			errorMsg:    "must specify either 'script' or 'command'",
 		},
+		{
+			name: "valid config with parallel steps",
+			configYAML: `
+hooks:
+  pre-commit:
+    - name: "lint"
+      command: "golangci-lint run"
+      description: "Lint"
+      parallel: true
+    - name: "test"
+      command: "go test ./..."
+      description: "Test"
+      parallel: true
+settings:
+  parallel_workers: 2
+`,
+			expectError: false,
+		},
+		{
+			name: "invalid config - negative parallel workers",
+			configYAML: `
+hooks:
+  pre-commit:
+    - name: "test"
+      command: "go test ./..."
+      description: "Test"
+settings:
+  parallel_workers: -1
+`,
+			expectError: true,
+			errorMsg:    "parallel_workers must not be negative",
+		},
+		{
+			name: "invalid config - bad step timeout",
+			configYAML: `
+hooks:
+  pre-push:
+    - name: "test"
+      command: "go test ./..."
+      description: "Test"
+      timeout: "soon"
+`,
+			expectError: true,
+			errorMsg:    "invalid timeout \"soon\"",
+		},
+		{
+			name: "invalid config - bad default timeout",
+			configYAML: `
+hooks:
+  pre-push:
+    - name: "test"
+      command: "go test ./..."
+      description: "Test"
+settings:
+  timeout: "-5s"
+`,
+			expectError: true,
+			errorMsg:    "settings.timeout: invalid timeout",
+		},
+		{
+			name: "invalid config - working_dir outside the repository",
+			configYAML: `
+hooks:
+  pre-commit:
+    - name: "test"
+      command: "go test ./..."
+      description: "Test"
+      working_dir: "../other"
+`,
+			expectError: true,
+			errorMsg:    "must be inside the repository",
+		},
+		{
+			name: "invalid config - absolute working_dir",
+			configYAML: `
+hooks:
+  pre-commit:
+    - name: "test"
+      command: "go test ./..."
+      description: "Test"
+      working_dir: "/srv/api"
+`,
+			expectError: true,
+			errorMsg:    "must be relative to the repository root",
+		},
+		{
+			name: "invalid config - unknown preserve_existing mode",
+			configYAML: `
+hooks:
+  pre-commit:
+    - name: "test"
+      command: "go test ./..."
+      description: "Test"
+settings:
+  preserve_existing: keep
+`,
+			expectError: true,
+			errorMsg:    "settings.preserve_existing must be",
+		},
+		{
+			name: "invalid config - unknown chain_order",
+			configYAML: `
+hooks:
+  pre-commit:
+    - name: "test"
+      command: "go test ./..."
+      description: "Test"
+settings:
+  preserve_existing: chain
+  chain_order: during
+`,
+			expectError: true,
+			errorMsg:    "settings.chain_order must be",
+		},
+		{
+			name: "invalid config - unknown lockfile mode",
+			configYAML: `
+settings:
+  lockfile: always
+`,
+			expectError: true,
+			errorMsg:    "settings.lockfile must be",
+		},
+		{
+			name: "invalid config - pass_filenames on hook without files",
+			configYAML: `
+hooks:
+  commit-msg:
+    - name: "lint"
+      command: "lint"
+      description: "Lint"
+      pass_filenames: true
+`,
+			expectError: true,
+			errorMsg:    "pass_filenames is only supported for pre-commit and pre-push",
+		},
+		{
+			name: "invalid config - malformed if expression",
+			configYAML: `
+hooks:
+  pre-commit:
+    - name: "lint"
+      command: "lint"
+      description: "Lint"
+      if: 'branch = "main"'
+`,
+			expectError: true,
+			errorMsg:    "hook pre-commit[0] (lint): invalid if: unexpected '='",
+		},
+		{
+			name: "invalid config - only_refs outside pre-push",
+			configYAML: `
+hooks:
+  pre-commit:
+    - name: "lint"
+      command: "lint"
+      description: "Lint"
+      only_refs: refs/heads/main
+`,
+			expectError: true,
+			errorMsg:    "only_refs is only supported for pre-push",
+		},
+		{
+			name: "invalid config - file_list outside pre-push",
+			configYAML: `
+hooks:
+  pre-commit:
+    - name: "lint"
+      command: "lint"
+      description: "Lint"
+      file_list: true
+`,
+			expectError: true,
+			errorMsg:    "file_list is only supported for pre-push",
+		},
+		{
+			name: "invalid config - restage without fixer",
+			configYAML: `
+hooks:
+  pre-commit:
+    - name: "fmt"
+      command: "gofmt -w ."
+      description: "Format"
+      restage: true
+`,
+			expectError: true,
+			errorMsg:    "restage requires fixer: true",
+		},
+		{
+			name: "invalid config - fixer outside pre-commit",
+			configYAML: `
+hooks:
+  pre-push:
+    - name: "fmt"
+      command: "gofmt -w ."
+      description: "Format"
+      fixer: true
+`,
+			expectError: true,
+			errorMsg:    "fixer is only supported for pre-commit",
+		},
+		{
+			name: "invalid config - parallel fixer",
+			configYAML: `
+hooks:
+  pre-commit:
+    - name: "fmt"
+      command: "gofmt -w ."
+      description: "Format"
+      fixer: true
+      parallel: true
+`,
+			expectError: true,
+			errorMsg:    "fixer steps cannot run in parallel",
+		},
+		{
+			name: "valid config with current version",
+			configYAML: `
+version: 3
+hooks:
+  pre-commit:
+    - name: "test"
+      command: "go test ./..."
+      description: "Test"
+`,
+			expectError: false,
+		},
+		{
+			name: "invalid config - outdated version",
+			configYAML: `
+version: 2
+hooks:
+  pre-commit:
+    - name: "test"
+      script: "go test ./..."
+      description: "Test"
+`,
+			expectError: true,
+			errorMsg:    "run 'hooky migrate'",
+		},
+		{
+			name: "invalid config - hooks_directory",
+			configYAML: `
+hooks_directory: "hooks"
+hooks:
+  pre-commit:
+    - name: "test"
+      script: "test.sh"
+      description: "Test"
+`,
+			expectError: true,
+			errorMsg:    "hooks_directory was removed",
+		},
+		{
+			name: "invalid config - newer version",
+			configYAML: `
+version: 4
+`,
+			expectError: true,
+			errorMsg:    "newer than this hooky supports",
+		},
 		{
 			name: "invalid YAML",
 			configYAML: `
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hookArgVars names the arguments git passes to each hook, in order, as the
+// HOOKY_* variables that steps receive them in.
+var hookArgVars = map[string][]string{
+	"applypatch-msg":     {"HOOKY_COMMIT_MSG_FILE"},
+	"commit-msg":         {"HOOKY_COMMIT_MSG_FILE"},
+	"prepare-commit-msg": {"HOOKY_COMMIT_MSG_FILE", "HOOKY_COMMIT_SOURCE", "HOOKY_COMMIT_SHA"},
+	"pre-rebase":         {"HOOKY_UPSTREAM", "HOOKY_REBASED_BRANCH"},
+	"post-checkout":      {"HOOKY_PREV_HEAD", "HOOKY_NEW_HEAD", "HOOKY_BRANCH_CHECKOUT"},
+	"post-merge":         {"HOOKY_SQUASH"},
+	"pre-push":           {"HOOKY_REMOTE", "HOOKY_REMOTE_URL"},
+	"update":             {"HOOKY_REF", "HOOKY_OLD_REV", "HOOKY_NEW_REV"},
+	"post-rewrite":       {"HOOKY_REWRITE_COMMAND"},
+}
+
+// fileListVars names the variable holding the path of the changed file list
+// for hooks that have one.
+var fileListVars = map[string]string{
+	"pre-commit": "HOOKY_STAGED_FILES",
+	"pre-push":   "HOOKY_PUSHED_FILES",
+}
+
+// hookVars returns the variables describing the hook invocation to script:
+// the hook and step names, the repository root, git's hook arguments by
+// name and the path of the changed file list.
+func (r *hookRun) hookVars(script HookScript) map[string]string {
+	vars := map[string]string{
+		"HOOKY_HOOK":      r.hookName,
+		"HOOKY_STEP":      script.Name,
+		"HOOKY_REPO_ROOT": r.root,
+	}
+
+	for i, name := range hookArgVars[r.hookName] {
+		if i >= len(r.args) {
+			break
+		}
+		value := r.args[i]
+		// git passes the message file relative to the repository root,
+		// which is not where steps with a working_dir run
+		if name == "HOOKY_COMMIT_MSG_FILE" && !filepath.IsAbs(value) {
+			value = filepath.Join(r.root, value)
+		}
+		vars[name] = value
+	}
+
+	if r.filesList != "" {
+		vars[fileListVars[r.hookName]] = r.filesList
+	}
+	return vars
+}
+
+// writeFileList writes files, NUL-separated, to a new temporary file and
+// returns its path. The caller removes the file.
+func writeFileList(files []string) (string, error) {
+	file, err := os.CreateTemp("", "hooky-files-*")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var list strings.Builder
+	for _, name := range files {
+		list.WriteString(name)
+		list.WriteByte(0)
+	}
+	if _, err := file.WriteString(list.String()); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHookVars(t *testing.T) {
+	tests := []struct {
+		name      string
+		hookName  string
+		args      []string
+		filesList string
+		expected  map[string]string
+	}{
+		{
+			name:     "post-checkout arguments",
+			hookName: "post-checkout",
+			args:     []string{"abc", "def", "1"},
+			expected: map[string]string{
+				"HOOKY_PREV_HEAD":       "abc",
+				"HOOKY_NEW_HEAD":        "def",
+				"HOOKY_BRANCH_CHECKOUT": "1",
+			},
+		},
+		{
+			name:     "relative message file is made absolute",
+			hookName: "commit-msg",
+			args:     []string{".git/COMMIT_EDITMSG"},
+			expected: map[string]string{
+				"HOOKY_COMMIT_MSG_FILE": filepath.Join("/repo", ".git/COMMIT_EDITMSG"),
+			},
+		},
+		{
+			name:     "missing optional arguments are unset",
+			hookName: "prepare-commit-msg",
+			args:     []string{"/tmp/msg"},
+			expected: map[string]string{
+				"HOOKY_COMMIT_MSG_FILE": "/tmp/msg",
+			},
+		},
+		{
+			name:      "pre-push remote and pushed files",
+			hookName:  "pre-push",
+			args:      []string{"origin", "git@example.com:repo.git"},
+			filesList: "/tmp/hooky-files-1",
+			expected: map[string]string{
+				"HOOKY_REMOTE":       "origin",
+				"HOOKY_REMOTE_URL":   "git@example.com:repo.git",
+				"HOOKY_PUSHED_FILES": "/tmp/hooky-files-1",
+			},
+		},
+		{
+			name:     "hooks without arguments",
+			hookName: "post-commit",
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := &hookRun{hookName: tt.hookName, args: tt.args, root: "/repo", filesList: tt.filesList}
+			vars := run.hookVars(HookScript{Name: "step"})
+
+			expected := map[string]string{
+				"HOOKY_HOOK":      tt.hookName,
+				"HOOKY_STEP":      "step",
+				"HOOKY_REPO_ROOT": "/repo",
+			}
+			for name, value := range tt.expected {
+				expected[name] = value
+			}
+			if !reflect.DeepEqual(vars, expected) {
+				t.Errorf("Expected %v, got %v", expected, vars)
+			}
+		})
+	}
+}
+
+func TestHookContext(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "context"
+      command: "echo \"${HOOKY_HOOK} $HOOKY_STEP $HOOKY_REPO_ROOT\" > context.txt && tr '\\0' '\\n' < \"${HOOKY_STAGED_FILES}\" > staged.txt && echo \"$HOOKY_STAGED_FILES\" > list.txt"
+      description: "Reads the hook context"
+  commit-msg:
+    - name: "message"
+      command: "cp \"$HOOKY_COMMIT_MSG_FILE\" message.txt && echo copied"
+      description: "Finds the message file from its working directory"
+      working_dir: "app"
+settings:
+  # ${HOOKY_...} is set when the step runs, not when the file is loaded
+  strict_variables: true
+`)
+	writeFiles(t, tmpDir, "a.go", "app/b.go", "unstaged.go")
+	gitRun(t, tmpDir, "add", "a.go", "app/b.go")
+	// As when git commits from a step of another hook
+	t.Setenv("HOOKY_STAGED_FILES", filepath.Join(tmpDir, "outer-list"))
+
+	root, err := repoRoot()
+	if err != nil {
+		t.Fatalf("Failed to find repository root: %v", err)
+	}
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-commit", nil); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+	if content := readFile(t, tmpDir, "context.txt"); content != "pre-commit context "+root+"\n" {
+		t.Errorf("Unexpected hook context: %q", content)
+	}
+	if content := readFile(t, tmpDir, "staged.txt"); content != "a.go\napp/b.go\n" {
+		t.Errorf("Unexpected staged file list: %q", content)
+	}
+
+	writeFile(t, tmpDir, ".git/COMMIT_EDITMSG", "feat: add context\n")
+	if err := hm.RunHook("commit-msg", []string{".git/COMMIT_EDITMSG"}); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+	if content := readFile(t, tmpDir, "app/message.txt"); content != "feat: add context\n" {
+		t.Errorf("Unexpected message: %q", content)
+	}
+
+	// The file list does not outlive the hook
+	list := strings.TrimSpace(readFile(t, tmpDir, "list.txt"))
+	if _, err := os.Stat(list); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed, got: %v", list, err)
+	}
+}
+
+func TestPushedFilesOnlyWhenNeeded(t *testing.T) {
+	tests := []struct {
+		name     string
+		step     string
+		wantList bool
+	}{
+		{
+			name: "plain step",
+			step: `command: "echo plain"`,
+		},
+		{
+			name:     "if: with changed()",
+			step:     "command: \"echo go\"\n      if: 'changed(\"*.go\")'",
+			wantList: true,
+		},
+		{
+			name:     "files",
+			step:     "command: \"echo go\"\n      files: \"*.go\"",
+			wantList: true,
+		},
+		{
+			name:     "file_list",
+			step:     "command: \"echo lint\"\n      file_list: true",
+			wantList: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := setupRunRepo(t, `
+hooks:
+  pre-push:
+    - name: "step"
+      `+tt.step+`
+    - name: "list"
+      command: "tr '\\0' '\\n' < \"${HOOKY_PUSHED_FILES:-/dev/null}\" > list.txt; true"
+`)
+			writeFiles(t, tmpDir, "main.go")
+			gitRun(t, tmpDir, "add", "main.go")
+			gitRun(t, tmpDir, "commit", "-m", "main")
+			head := strings.TrimSpace(gitRun(t, tmpDir, "rev-parse", "HEAD"))
+
+			traceDir := t.TempDir()
+			t.Setenv("GIT_TRACE", filepath.Join(traceDir, "trace"))
+			withStdin(t, "refs/heads/main "+head+" refs/heads/main "+strings.Repeat("0", 40)+"\n")
+
+			if err := NewHookManager("hooky.yaml", false).RunHook("pre-push", []string{"origin", "unused"}); err != nil {
+				t.Fatalf("RunHook failed: %v", err)
+			}
+			if diff := strings.Contains(readFile(t, traceDir, "trace"), "log --format= --name-only"); diff != tt.wantList {
+				t.Errorf("Expected pushed files to be looked up: %v, got %v", tt.wantList, diff)
+			}
+			if list := readFile(t, tmpDir, "list.txt"); (list == "main.go\n") != tt.wantList {
+				t.Errorf("Expected the file list to be set: %v, got %q", tt.wantList, list)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// readEnvFile reads variables from a dotenv-style file: NAME=value lines,
+// optionally starting with "export", with blank lines and # comments
+// ignored. Values may be wrapped in single or double quotes.
+func readEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !isVarName(name) {
+			return nil, fmt.Errorf("%s:%d: expected NAME=value", path, lineNo)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// stepVars returns the variables script sets on top of hooky's own
+// environment. Later sources override earlier ones: settings.env_file,
+// settings.env, the step's env_file, then its env. Env files of the
+// settings are relative to the repository root, those of a step to its
+// working directory.
+func (hm *HookManager) stepVars(script HookScript) (map[string]string, error) {
+	vars, err := mergeStepVars(hm.config.Settings, script)
+	if err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// mergeStepVars merges the variables of settings and script in the order
+// stepVars documents. When an env file cannot be read, it returns the
+// variables merged so far along with the error.
+func mergeStepVars(settings Settings, script HookScript) (map[string]string, error) {
+	vars := make(map[string]string)
+
+	if settings.EnvFile != "" {
+		fileVars, err := readEnvFile(repoPath(settings.EnvFile))
+		if err != nil {
+			return vars, fmt.Errorf("settings.env_file: %w", err)
+		}
+		for name, value := range fileVars {
+			vars[name] = value
+		}
+	}
+	for name, value := range settings.Env {
+		vars[name] = value
+	}
+
+	if script.EnvFile != "" {
+		fileVars, err := readEnvFile(stepPath(script, script.EnvFile))
+		if err != nil {
+			return vars, fmt.Errorf("env_file of %s: %w", script.Name, err)
+		}
+		for name, value := range fileVars {
+			vars[name] = value
+		}
+	}
+	for name, value := range script.Env {
+		vars[name] = value
+	}
+
+	return vars, nil
+}
+
+// stepEnviron returns the environment script runs with.
+func (hm *HookManager) stepEnviron(script HookScript) ([]string, error) {
+	vars, err := hm.stepVars(script)
+	if err != nil {
+		return nil, err
+	}
+	return appendVars(os.Environ(), vars), nil
+}
+
+// appendVars appends vars to environ in a stable order. exec uses the last
+// value of a repeated variable, so vars override environ.
+func appendVars(environ []string, vars map[string]string) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		environ = append(environ, name+"="+vars[name])
+	}
+	return environ
+}
+
+// commandProgram returns the program a command line runs, and whether it is
+// found in the PATH the step runs with: vars["PATH"] when the step sets it,
+// hooky's own PATH otherwise. Variable assignments and an env invocation in
+// front of the program are skipped, so "env CGO_ENABLED=0 go build" runs go.
+func commandProgram(script HookScript, line string, vars map[string]string) (string, bool) {
+	program := programOf(line)
+
+	pathVar, ok := vars["PATH"]
+	if !ok || strings.ContainsAny(program, `/\`) {
+		_, err := exec.LookPath(program)
+		return program, err == nil
+	}
+
+	for _, dir := range filepath.SplitList(pathVar) {
+		if dir == "" {
+			dir = "."
+		}
+		if _, err := exec.LookPath(filepath.Join(stepPath(script, dir), program)); err == nil {
+			return program, true
+		}
+	}
+	return program, false
+}
+
+// programOf returns the program a shell command line starts, skipping
+// leading NAME=value assignments and an env invocation with its options and
+// assignments.
+func programOf(line string) string {
+	fields := strings.Fields(line)
+	i := 0
+	for i < len(fields) && isAssignment(fields[i]) {
+		i++
+	}
+
+	if i < len(fields) && filepath.Base(fields[i]) == "env" {
+		i++
+	env:
+		for i < len(fields) {
+			switch field := fields[i]; {
+			case field == "-u" || field == "--unset" || field == "-C" || field == "--chdir":
+				i += 2
+			case strings.HasPrefix(field, "-") || isAssignment(field):
+				i++
+			default:
+				break env
+			}
+		}
+	}
+
+	if i < len(fields) {
+		return fields[i]
+	}
+	if len(fields) > 0 {
+		return fields[0]
+	}
+	return line
+}
+
+// isAssignment reports whether a command line word assigns a variable.
+func isAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	return ok && isVarName(name)
+}
+
+// isVarName reports whether name is a valid environment variable name.
+func isVarName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; c != '_' && !isAlpha(c) && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
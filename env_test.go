@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadEnvFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected map[string]string
+		errorMsg string
+	}{
+		{
+			name: "assignments, comments and quotes",
+			content: `# Build settings
+GOFLAGS=-mod=mod
+export CGO_ENABLED=0
+
+QUOTED="two words"
+SINGLE='$NOT_EXPANDED'
+EMPTY=
+`,
+			expected: map[string]string{
+				"GOFLAGS":     "-mod=mod",
+				"CGO_ENABLED": "0",
+				"QUOTED":      "two words",
+				"SINGLE":      "$NOT_EXPANDED",
+				"EMPTY":       "",
+			},
+		},
+		{
+			name:     "line without assignment",
+			content:  "GOFLAGS=-mod=mod\nnot an assignment\n",
+			errorMsg: ".env:2: expected NAME=value",
+		},
+		{
+			name:     "invalid name",
+			content:  "1ST=value\n",
+			errorMsg: ".env:1: expected NAME=value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write env file: %v", err)
+			}
+
+			vars, err := readEnvFile(path)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got: %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readEnvFile failed: %v", err)
+			}
+
+			if len(vars) != len(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, vars)
+			}
+			for name, value := range tt.expected {
+				if vars[name] != value {
+					t.Errorf("Expected %s=%q, got %q", name, value, vars[name])
+				}
+			}
+		})
+	}
+}
+
+func TestProgramOf(t *testing.T) {
+	tests := map[string]string{
+		"go test ./...":                         "go",
+		"CGO_ENABLED=0 go build":                "go",
+		"env CGO_ENABLED=0 GOOS=linux go build": "go",
+		"/usr/bin/env -i PATH=/bin ls":          "ls",
+		"env -u GOFLAGS -- go vet":              "go",
+		"env":                                   "env",
+		"A=1":                                   "A=1",
+		"./bin/tool --flag=x":                   "./bin/tool",
+	}
+	for line, expected := range tests {
+		if got := programOf(line); got != expected {
+			t.Errorf("programOf(%q) = %q, expected %q", line, got, expected)
+		}
+	}
+}
+
+func TestStepEnv(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "vars"
+      command: "echo $FROM_SETTINGS_FILE $FROM_SETTINGS $FROM_STEP_FILE $FROM_STEP > vars.txt"
+      description: "Later sources override earlier ones"
+      working_dir: "app"
+      env_file: "step.env"
+      env:
+        FROM_STEP: "step"
+    - name: "tool"
+      command: "env TOOL_FLAG=1 hooky-test-tool"
+      description: "Found through the step's PATH"
+      env:
+        PATH: "tools:${PATH}"
+settings:
+  env_file: "settings.env"
+  env:
+    FROM_SETTINGS: "settings"
+    FROM_STEP: "overridden"
+`)
+	writeFile(t, tmpDir, "settings.env", "FROM_SETTINGS_FILE=settings-file\nFROM_SETTINGS=overridden\nFROM_STEP_FILE=overridden\n")
+	writeFile(t, tmpDir, "app/step.env", "FROM_STEP_FILE=step-file\n")
+	writeFile(t, tmpDir, "tools/hooky-test-tool", "#!/bin/sh\necho \"tool $TOOL_FLAG\" > tool.txt\n")
+	os.Chmod(filepath.Join(tmpDir, "tools/hooky-test-tool"), 0755)
+	writeFiles(t, tmpDir, "app/main.go")
+	gitRun(t, tmpDir, "add", "app/main.go")
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.init(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	if err := hm.validateScripts(); err != nil {
+		t.Errorf("Expected the tool to be found through the step's PATH, got: %v", err)
+	}
+
+	if err := hm.RunHook("pre-commit", nil); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+	if content := readFile(t, tmpDir, "app/vars.txt"); content != "settings-file settings step-file step\n" {
+		t.Errorf("Unexpected step environment: %q", content)
+	}
+	if content := readFile(t, tmpDir, "tool.txt"); content != "tool 1\n" {
+		t.Errorf("Unexpected tool output: %q", content)
+	}
+
+	// A missing env file is reported before installing
+	os.Remove(filepath.Join(tmpDir, "app/step.env"))
+	if err := hm.validateScripts(); err == nil || !strings.Contains(err.Error(), "env_file of vars") {
+		t.Errorf("Expected a missing env file to be reported, got: %v", err)
+	}
+	var stepErr *StepError
+	if err := hm.RunHook("pre-commit", nil); !errors.As(err, &stepErr) || !strings.Contains(stepErr.Err.Error(), "step.env") {
+		t.Errorf("Expected the step to fail without its env file, got: %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// hasChangedFiles reports whether hookName has a list of changed files that
+// steps can filter on or receive as arguments.
+func hasChangedFiles(hookName string) bool {
+	return hookName == "pre-commit" || hookName == "pre-push"
+}
+
+// changedFiles returns the repository-relative paths a hook invocation is
+// about: the staged files for pre-commit and the files in the pushed commits
+// for pre-push, taken from updates when git passed them. The boolean is
+// false for hooks without such a file list.
+func changedFiles(hookName string, args []string, updates []refUpdate) ([]string, bool, error) {
+	switch hookName {
+	case "pre-commit":
+		files, err := stagedFiles()
+		return files, true, err
+	case "pre-push":
+		remote := ""
+		if len(args) > 0 {
+			remote = args[0]
+		}
+		if updates != nil {
+			files, err := updatedFiles(remote, updates)
+			return files, true, err
+		}
+		files, err := pushedFiles(remote)
+		return files, true, err
+	default:
+		return nil, false, nil
+	}
+}
+
+// stagedFiles returns the files added, copied, modified or renamed in the index.
+func stagedFiles() ([]string, error) {
+	return gitFileList("", "diff", "--cached", "--name-only", "--diff-filter=ACMR", "-z")
+}
+
+// pushedFiles approximates the files changed by a push to remote as those
+// touched by commits on HEAD that no branch of the remote contains yet. It
+// is used when the pushed refs are unknown, such as when running pre-push
+// by hand.
+func pushedFiles(remote string) ([]string, error) {
+	return gitFileList("", "log", "--format=", "--name-only", "--no-renames", "--diff-filter=ACMR", "-z", "HEAD", "--not", remoteRefs(remote))
+}
+
+// filesUnder returns the files below dir, with paths relative to it. Both
+// the files and dir are relative to the repository root.
+func filesUnder(files []string, dir string) []string {
+	dir = path.Clean(filepath.ToSlash(dir))
+	if dir == "." {
+		return files
+	}
+
+	var under []string
+	for _, file := range files {
+		if rel, ok := strings.CutPrefix(file, dir+"/"); ok {
+			under = append(under, rel)
+		}
+	}
+	return under
+}
+
+// filterFiles returns the files matching at least one include pattern (or
+// all files if there are none) and no exclude pattern.
+func filterFiles(files []string, include, exclude Patterns) []string {
+	var matched []string
+	for _, file := range files {
+		if len(include) > 0 && !matchAny(include, file) {
+			continue
+		}
+		if matchAny(exclude, file) {
+			continue
+		}
+		matched = append(matched, file)
+	}
+	return matched
+}
+
+func matchAny(patterns Patterns, file string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a repository-relative, slash-separated path against a
+// glob pattern. Patterns without a slash match the file's base name at any
+// depth, "**" matches any number of directories, and a trailing slash
+// matches everything below a directory.
+func matchGlob(pattern, file string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(file))
+		return matched
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(file, "/"))
+}
+
+func matchSegments(pattern, file []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(file); i++ {
+				if matchSegments(pattern, file[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(file) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], file[0]); !matched {
+			return false
+		}
+		pattern, file = pattern[1:], file[1:]
+	}
+	return len(file) == 0
+}
+
+// batchFiles splits files into batches whose combined argument size stays
+// within limit bytes, so that each batch fits on one command line. Every
+// batch holds at least one file, even if that file alone exceeds the limit.
+func batchFiles(files []string, limit int) [][]string {
+	var batches [][]string
+	var batch []string
+	size := 0
+	for _, file := range files {
+		// Each argument costs its bytes, a terminating NUL and a pointer
+		cost := len(file) + 1 + 8
+		if len(batch) > 0 && size+cost > limit {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, file)
+		size += cost
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// argLimit returns how many bytes of file arguments can be appended to line
+// when it runs through shellCommand with the environment env, after
+// accounting for the shell invocation and, where it shares the OS argument
+// space, the environment.
+func argLimit(line string, env []string) int {
+	used := 0
+	for _, arg := range shellCommand(line, nil).Args {
+		used += len(arg) + 1 + 8
+	}
+	if envSharesArgSpace {
+		for _, env := range env {
+			used += len(env) + 1 + 8
+		}
+	}
+	return maxArgLength - used
+}
+
+// validateGlob reports malformed patterns such as an unclosed '['.
+func validateGlob(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("empty file pattern")
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid file pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
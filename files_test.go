@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		file     string
+		expected bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/hooky/main.go", true},
+		{"*.go", "main.go.orig", false},
+		{"docs/*.md", "docs/index.md", true},
+		{"docs/*.md", "docs/guide/intro.md", false},
+		{"docs/**", "docs/guide/intro.md", true},
+		{"docs/", "docs/guide/intro.md", true},
+		{"docs/", "src/docs.go", false},
+		{"**/*.md", "README.md", true},
+		{"**/*.md", "a/b/c/notes.md", true},
+		{"services/**/*_test.go", "services/api/handler_test.go", true},
+		{"services/**/*_test.go", "services/handler_test.go", true},
+		{"services/**/*_test.go", "web/handler_test.go", false},
+		{"hooks/?int.sh", "hooks/lint.sh", true},
+		{"[Mm]akefile", "Makefile", true},
+		{"go.mod", "tools/go.mod", true},
+		{"./go.mod", "go.mod", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.file, func(t *testing.T) {
+			if got := matchGlob(tt.pattern, tt.file); got != tt.expected {
+				t.Errorf("matchGlob(%q, %q) = %v, expected %v", tt.pattern, tt.file, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFilterFiles(t *testing.T) {
+	files := []string{"main.go", "main_test.go", "docs/index.md", "README.md", "vendor/lib/lib.go"}
+
+	tests := []struct {
+		name     string
+		include  Patterns
+		exclude  Patterns
+		expected []string
+	}{
+		{"no patterns", nil, nil, files},
+		{"include only", Patterns{"*.go"}, nil, []string{"main.go", "main_test.go", "vendor/lib/lib.go"}},
+		{"include and exclude", Patterns{"*.go"}, Patterns{"vendor/", "*_test.go"}, []string{"main.go"}},
+		{"exclude only", nil, Patterns{"*.md"}, []string{"main.go", "main_test.go", "vendor/lib/lib.go"}},
+		{"nothing matches", Patterns{"*.py"}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filterFiles(files, tt.include, tt.exclude)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFilesUnder(t *testing.T) {
+	files := []string{"main.go", "services/api/main.go", "services/api/cmd/serve.go", "services/apix/main.go"}
+
+	tests := []struct {
+		dir      string
+		expected []string
+	}{
+		{".", files},
+		{"services/api", []string{"main.go", "cmd/serve.go"}},
+		{"services/api/", []string{"main.go", "cmd/serve.go"}},
+		{"./services/api", []string{"main.go", "cmd/serve.go"}},
+		{"web", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			got := filesUnder(files, tt.dir)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateGlob(t *testing.T) {
+	if err := validateGlob("src/**/*.go"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := validateGlob("src/[a-"); err == nil {
+		t.Error("Expected error for malformed pattern")
+	}
+	if err := validateGlob(""); err == nil {
+		t.Error("Expected error for empty pattern")
+	}
+}
+
+func TestBatchFiles(t *testing.T) {
+	// Each of these costs len+1+8 = 10 bytes
+	files := []string{"a", "b", "c", "d", "e"}
+
+	tests := []struct {
+		name     string
+		limit    int
+		expected [][]string
+	}{
+		{"everything fits", 1000, [][]string{{"a", "b", "c", "d", "e"}}},
+		{"two per batch", 25, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+		{"exact fit", 30, [][]string{{"a", "b", "c"}, {"d", "e"}}},
+		{"limit below one file", 1, [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := batchFiles(files, tt.limit)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if batches := batchFiles(nil, 100); len(batches) != 0 {
+		t.Errorf("Expected no batches for no files, got %v", batches)
+	}
+}
+
+func TestArgLimit(t *testing.T) {
+	short := argLimit("lint", os.Environ())
+	long := argLimit("lint --with --many --extra --flags", os.Environ())
+
+	if short <= 0 || short >= maxArgLength {
+		t.Errorf("Expected limit between 0 and %d, got %d", maxArgLength, short)
+	}
+	if long >= short {
+		t.Errorf("Expected a longer command line to leave less room (%d >= %d)", long, short)
+	}
+	if envSharesArgSpace {
+		if more := argLimit("lint", append(os.Environ(), "HOOKY_STEP=lint")); more != short-len("HOOKY_STEP=lint")-1-8 {
+			t.Errorf("Expected the environment to count towards the limit, got %d for %d", more, short)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FixerError is returned when a fixer step modified staged files and the
+// modifications were not added to the index.
+type FixerError struct {
+	Files []string
+}
+
+func (e *FixerError) Error() string {
+	return fmt.Sprintf("modified staged files: %s", strings.Join(e.Files, ", "))
+}
+
+// fileSnapshot maps repository-relative paths to a hash of their content in
+// the working tree. Missing files have an empty hash.
+type fileSnapshot map[string]string
+
+func snapshotFiles(root string, files []string) (fileSnapshot, error) {
+	snapshot := make(fileSnapshot, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(root, file))
+		if os.IsNotExist(err) {
+			snapshot[file] = ""
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(content)
+		snapshot[file] = hex.EncodeToString(sum[:])
+	}
+	return snapshot, nil
+}
+
+// modified returns the files whose content differs from the snapshot, in
+// the order given.
+func (s fileSnapshot) modified(root string, files []string) ([]string, error) {
+	current, err := snapshotFiles(root, files)
+	if err != nil {
+		return nil, err
+	}
+
+	var modified []string
+	for _, file := range files {
+		if current[file] != s[file] {
+			modified = append(modified, file)
+		}
+	}
+	return modified, nil
+}
+
+// runFixer runs a step that may rewrite staged files. Files it modifies are
+// added back to the index when the step has restage set. Otherwise, or when
+// a file also has unstaged changes that restaging would commit by accident,
+// the step fails and shows what it changed. Files whose unstaged changes
+// were stashed count as having unstaged changes.
+func (r *hookRun) runFixer(ctx context.Context, script HookScript, stdin io.Reader, stdout, stderr io.Writer) error {
+	staged := r.files
+
+	unstaged, err := gitFileList(r.root, "diff", "--name-only", "-z", "--")
+	if err != nil {
+		return err
+	}
+	unstaged = append(unstaged, r.stashed...)
+
+	before, err := snapshotFiles(r.root, staged)
+	if err != nil {
+		return fmt.Errorf("failed to read staged files: %w", err)
+	}
+
+	if err := r.runScript(ctx, script, stdin, stdout, stderr); err != nil {
+		return err
+	}
+
+	modified, err := before.modified(r.root, staged)
+	if err != nil {
+		return fmt.Errorf("failed to read staged files: %w", err)
+	}
+	if len(modified) == 0 {
+		return nil
+	}
+
+	var restage, unsafe []string
+	for _, file := range modified {
+		if script.Restage && !containsFile(unstaged, file) {
+			restage = append(restage, file)
+		} else {
+			unsafe = append(unsafe, file)
+		}
+	}
+
+	if len(restage) > 0 {
+		if _, err := runGit(r.root, append([]string{"add", "--"}, restage...)...); err != nil {
+			return fmt.Errorf("failed to restage modified files: %w", err)
+		}
+		fmt.Fprintf(stdout, "Restaged files modified by %s: %s\n", script.Name, strings.Join(restage, ", "))
+	}
+
+	if len(unsafe) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "%s modified these staged files:\n", script.Name)
+	for _, file := range unsafe {
+		note := ""
+		if script.Restage {
+			note = " (not restaged: it also has unstaged changes)"
+		}
+		fmt.Fprintf(stdout, "  %s%s\n", file, note)
+	}
+	if diff, err := runGit(r.root, append([]string{"diff", "--no-ext-diff", "--"}, unsafe...)...); err == nil {
+		fmt.Fprint(stdout, diff)
+	}
+	fmt.Fprintf(stdout, "Review the changes, stage them with 'git add' and commit again.\n")
+
+	return &FixerError{Files: unsafe}
+}
+
+func containsFile(files []string, file string) bool {
+	for _, f := range files {
+		if f == file {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunHookFixer(t *testing.T) {
+	tests := []struct {
+		name          string
+		step          string
+		unstaged      bool
+		stash         bool
+		expectedFiles []string
+		expectedIndex string
+	}{
+		{
+			name: "restage modified files",
+			step: `
+      fixer: true
+      restage: true`,
+			expectedIndex: "fixed\n",
+		},
+		{
+			name: "fail without restage",
+			step: `
+      fixer: true`,
+			expectedFiles: []string{"a.go"},
+			expectedIndex: "unformatted\n",
+		},
+		{
+			name: "do not restage files with unstaged changes",
+			step: `
+      fixer: true
+      restage: true`,
+			unstaged:      true,
+			expectedFiles: []string{"a.go"},
+			expectedIndex: "unformatted\n",
+		},
+		{
+			name: "do not restage files whose unstaged changes are stashed",
+			step: `
+      fixer: true
+      restage: true
+settings:
+  stash_unstaged: true`,
+			unstaged:      true,
+			stash:         true,
+			expectedFiles: []string{"a.go"},
+			expectedIndex: "unformatted\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "format"
+      command: "printf 'fixed\n' > a.go; printf 'fixed\n' > unstaged-only.go"
+      description: "Rewrites a.go"`+tt.step+`
+`)
+
+			writeFile(t, tmpDir, "a.go", "unformatted\n")
+			gitRun(t, tmpDir, "add", "a.go")
+			if tt.unstaged {
+				writeFile(t, tmpDir, "a.go", "unformatted\nunstaged edit\n")
+			}
+
+			hm := NewHookManager("hooky.yaml", false)
+			err := hm.RunHook("pre-commit", nil)
+
+			var fixerErr *FixerError
+			if tt.expectedFiles == nil {
+				if err != nil {
+					t.Fatalf("Expected the fixer to pass, got: %v", err)
+				}
+			} else if !errors.As(err, &fixerErr) {
+				t.Fatalf("Expected a FixerError, got: %v", err)
+			} else if !reflect.DeepEqual(fixerErr.Files, tt.expectedFiles) {
+				t.Errorf("Expected modified files %v, got %v", tt.expectedFiles, fixerErr.Files)
+			}
+
+			if index := gitRun(t, tmpDir, "show", ":a.go"); index != tt.expectedIndex {
+				t.Errorf("Expected staged content %q, got %q", tt.expectedIndex, index)
+			}
+			if staged := gitRun(t, tmpDir, "diff", "--cached", "--name-only"); strings.Contains(staged, "unstaged-only.go") {
+				t.Error("Files that were not staged should never be restaged")
+			}
+			if content := readFile(t, tmpDir, "a.go"); tt.stash && content != "unformatted\nunstaged edit\n" {
+				t.Errorf("Expected the unstaged changes to be restored, got %q", content)
+			}
+		})
+	}
+}
+
+func TestRunHookFixerNoChanges(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "format"
+      command: "true"
+      description: "Already formatted"
+      fixer: true
+`)
+
+	writeFile(t, tmpDir, "a.go", "formatted\n")
+	gitRun(t, tmpDir, "add", "a.go")
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-commit", nil); err != nil {
+		t.Errorf("A fixer that changes nothing should pass, got: %v", err)
+	}
+}
+
+func TestFileSnapshot(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, tmpDir, "same", "same\n")
+	writeFile(t, tmpDir, "changed", "before\n")
+	writeFile(t, tmpDir, "deleted", "deleted\n")
+
+	files := []string{"same", "changed", "deleted", "created"}
+	snapshot, err := snapshotFiles(tmpDir, files)
+	if err != nil {
+		t.Fatalf("snapshotFiles failed: %v", err)
+	}
+
+	writeFile(t, tmpDir, "changed", "after\n")
+	writeFile(t, tmpDir, "created", "created\n")
+	if err := os.Remove(filepath.Join(tmpDir, "deleted")); err != nil {
+		t.Fatalf("Failed to delete file: %v", err)
+	}
+
+	modified, err := snapshot.modified(tmpDir, files)
+	if err != nil {
+		t.Fatalf("modified failed: %v", err)
+	}
+
+	expected := []string{"changed", "deleted", "created"}
+	if !reflect.DeepEqual(modified, expected) {
+		t.Errorf("Expected %v, got %v", expected, modified)
+	}
+}
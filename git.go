@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// runGit runs git with args in dir (the current directory if empty) and
+// returns its standard output. Errors include git's standard error.
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return string(output), fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+// gitFileList runs a git command producing NUL-separated paths and returns
+// them without duplicates, in the order git reported them.
+func gitFileList(dir string, args ...string) ([]string, error) {
+	output, err := runGit(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, file := range strings.Split(output, "\x00") {
+		if file == "" || seen[file] {
+			continue
+		}
+		seen[file] = true
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// repoRoot returns the top-level directory of the current working tree.
+func repoRoot() (string, error) {
+	root, err := runGit("", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(root), nil
+}
+
+// worktreeGitDir returns the git directory of the current worktree, which
+// holds its index and other per-worktree state. It differs from the common
+// git directory in linked worktrees.
+func worktreeGitDir() (string, error) {
+	dir, err := runGit("", "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(dir), nil
+}
+
+// listWorktrees returns the paths of the repository's worktrees, starting
+// with the main one.
+func listWorktrees() ([]string, error) {
+	output, err := runGit("", "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+
+	var worktrees []string
+	for _, line := range strings.Split(output, "\n") {
+		if path, ok := strings.CutPrefix(line, "worktree "); ok {
+			worktrees = append(worktrees, path)
+		}
+	}
+	return worktrees, nil
+}
@@ -3,7 +3,7 @@
 
 echo "📝 Validating commit message..."
 
-commit_msg_file="$1"
+commit_msg_file="$HOOKY_COMMIT_MSG_FILE"
 commit_msg=$(cat "$commit_msg_file")
 
 # Skip validation for merge commits
@@ -4,7 +4,7 @@
 echo "🧹 Post-merge cleanup..."
 
 # Get merge information
-squash_merge="$1"
+squash_merge="$HOOKY_SQUASH"
 
 # Clean up common temporary files
 echo "  🗑️  Cleaning temporary files..."
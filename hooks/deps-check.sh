@@ -3,10 +3,10 @@
 
 echo "📦 Checking dependencies..."
 
-# Get information about the checkout
-prev_head="$1"
-new_head="$2"
-branch_checkout="$3"
+# Get information about the checkout from the variables hooky sets
+prev_head="$HOOKY_PREV_HEAD"
+new_head="$HOOKY_NEW_HEAD"
+branch_checkout="$HOOKY_BRANCH_CHECKOUT"
 
 # Skip if this is a file checkout, not a branch checkout
 if [ "$branch_checkout" = "0" ]; then
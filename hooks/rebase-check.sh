@@ -4,8 +4,8 @@
 echo "⚠️  Pre-rebase safety checks..."
 
 # Get the upstream and branch being rebased
-upstream="$1"
-branch="$2"
+upstream="$HOOKY_UPSTREAM"
+branch="$HOOKY_REBASED_BRANCH"
 
 # Check if we're on main/master branch (usually shouldn't rebase these)
 current_branch=$(git rev-parse --abbrev-ref HEAD)
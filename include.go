@@ -0,0 +1,287 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the on-disk form of a configuration file. Settings are kept
+// as a node so that only the keys a file sets override earlier files.
+type configFile struct {
+	Version  int                     `yaml:"version"`
+	Include  includeList             `yaml:"include"`
+	Extends  includeList             `yaml:"extends"`
+	Repos    []remoteRepo            `yaml:"repos"`
+	Hooks    map[string][]HookScript `yaml:"hooks"`
+	Settings yaml.Node               `yaml:"settings"`
+
+	// HooksDirectory is only read to reject files from hooky 1.0
+	HooksDirectory string `yaml:"hooks_directory"`
+}
+
+// includeEntry names a configuration file to include: a local path, or a
+// path inside a git repository at a given ref. In YAML a local include may
+// be written as a plain string.
+type includeEntry struct {
+	Path string `yaml:"path"`
+	Git  string `yaml:"git"`
+	Ref  string `yaml:"ref"`
+}
+
+func (e *includeEntry) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		e.Path = value.Value
+		return nil
+	}
+
+	type plain includeEntry
+	if err := value.Decode((*plain)(e)); err != nil {
+		return err
+	}
+	if e.Path == "" {
+		return fmt.Errorf("line %d: include needs a path", value.Line)
+	}
+	if e.Ref != "" && e.Git == "" {
+		return fmt.Errorf("line %d: include ref %q needs a git repository", value.Line, e.Ref)
+	}
+	return nil
+}
+
+// includeList is a list of includes. In YAML it may be a single entry.
+type includeList []includeEntry
+
+func (l *includeList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.SequenceNode {
+		var entry includeEntry
+		if err := value.Decode(&entry); err != nil {
+			return err
+		}
+		*l = includeList{entry}
+		return nil
+	}
+
+	var entries []includeEntry
+	if err := value.Decode(&entries); err != nil {
+		return err
+	}
+	*l = entries
+	return nil
+}
+
+// configSource locates a configuration file, either on the local filesystem
+// or, when Repo is set, inside a git repository at Ref.
+type configSource struct {
+	Repo string
+	Ref  string
+	Path string
+}
+
+func (s configSource) String() string {
+	if s.Repo == "" {
+		return s.Path
+	}
+	return fmt.Sprintf("%s@%s:%s", s.Repo, s.ref(), s.Path)
+}
+
+func (s configSource) ref() string {
+	if s.Ref == "" {
+		return "HEAD"
+	}
+	return s.Ref
+}
+
+// resolve returns the source of an include found in s. Local paths are
+// relative to the including file; in a file from a git repository they
+// refer to the same repository and ref.
+func (s configSource) resolve(entry includeEntry) configSource {
+	if entry.Git != "" {
+		return configSource{Repo: entry.Git, Ref: entry.Ref, Path: path.Clean(entry.Path)}
+	}
+	if s.Repo != "" {
+		return configSource{Repo: s.Repo, Ref: s.Ref, Path: path.Join(path.Dir(s.Path), entry.Path)}
+	}
+	if filepath.IsAbs(entry.Path) {
+		return configSource{Path: entry.Path}
+	}
+	return configSource{Path: filepath.Join(filepath.Dir(s.Path), filepath.FromSlash(entry.Path))}
+}
+
+func (s configSource) read(refresh bool) ([]byte, error) {
+	if s.Repo == "" {
+		return os.ReadFile(s.Path)
+	}
+
+	cache, err := fetchRepo(s.Repo, s.ref(), refresh)
+	if err != nil {
+		return nil, err
+	}
+	content, err := runGit("", "--git-dir="+cache, "show", cachedRef(s.ref())+":"+s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%s not found in %s at %s: %w", s.Path, s.Repo, s.ref(), err)
+	}
+	return []byte(content), nil
+}
+
+// IncludeError names the included file a configuration error comes from,
+// and the file that included it.
+type IncludeError struct {
+	File string
+	From string
+	Err  error
+}
+
+func (e *IncludeError) Error() string {
+	return fmt.Sprintf("%s (included from %s): %v", e.File, e.From, e.Err)
+}
+
+func (e *IncludeError) Unwrap() error {
+	return e.Err
+}
+
+// configLoader merges a configuration file and everything it includes into
+// config. Included files are merged first, in order, then the steps the
+// file uses from remote repositories, then its own, so the including file
+// has the last word:
+//   - steps are appended per hook, except that a step with the same name as
+//     an earlier step of the same hook replaces it in place;
+//   - each settings key a file sets overrides the value from earlier files.
+type configLoader struct {
+	config  *Config
+	refresh bool
+
+	// stack holds the files being loaded, outermost first, to detect cycles
+	stack []string
+}
+
+func (l *configLoader) load(source configSource) error {
+	name := source.String()
+	for i, loading := range l.stack {
+		if loading == name {
+			return fmt.Errorf("include cycle: %s -> %s", strings.Join(l.stack[i:], " -> "), name)
+		}
+	}
+	l.stack = append(l.stack, name)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	err := l.loadFile(source)
+	var includeErr *IncludeError
+	if err != nil && len(l.stack) > 1 && !errors.As(err, &includeErr) {
+		return &IncludeError{File: name, From: l.stack[len(l.stack)-2], Err: err}
+	}
+	return err
+}
+
+func (l *configLoader) loadFile(source configSource) error {
+	data, err := source.read(l.refresh)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if source.Repo != "" {
+		if err := l.recordCommit(source.Repo, source.ref()); err != nil {
+			return err
+		}
+	}
+
+	var file configFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if err := checkConfigVersion(file.Version, file.HooksDirectory); err != nil {
+		return err
+	}
+
+	for _, entry := range append(file.Extends, file.Include...) {
+		if err := l.load(source.resolve(entry)); err != nil {
+			return err
+		}
+	}
+
+	for _, repo := range file.Repos {
+		hooks, err := resolveRemoteRepo(repo)
+		if err != nil {
+			return err
+		}
+		if err := l.recordCommit(repo.Repo, repo.Rev); err != nil {
+			return err
+		}
+		if err := validateHookScripts(hooks); err != nil {
+			return fmt.Errorf("%s@%s: %w", repo.Repo, repo.Rev, err)
+		}
+
+		l.config.Files = append(l.config.Files, fmt.Sprintf("%s@%s:%s", repo.Repo, repo.Rev, remoteManifest))
+		for hookName, scripts := range hooks {
+			for _, script := range scripts {
+				l.mergeStep(hookName, script)
+			}
+		}
+	}
+
+	// Validate hook script configuration
+	if err := validateHookScripts(file.Hooks); err != nil {
+		return err
+	}
+
+	if !file.Settings.IsZero() {
+		if err := expandSettings(l.config, &file.Settings); err != nil {
+			return err
+		}
+		if err := file.Settings.Decode(&l.config.Settings); err != nil {
+			return fmt.Errorf("failed to parse config file: %w", err)
+		}
+		if err := validateSettings(l.config.Settings); err != nil {
+			return err
+		}
+	}
+
+	l.config.Files = append(l.config.Files, source.String())
+	for hookName, scripts := range file.Hooks {
+		for _, script := range scripts {
+			script.Source = source.String()
+			l.mergeStep(hookName, script)
+		}
+	}
+
+	return nil
+}
+
+// recordCommit notes the commit that ref of the repository at url, already
+// fetched into the cache, resolved to.
+func (l *configLoader) recordCommit(url, ref string) error {
+	cache, err := fetchRepo(url, ref, false)
+	if err != nil {
+		return err
+	}
+	commit, err := cachedCommit(cache, ref)
+	if err != nil {
+		return fmt.Errorf("%s is not a commit in %s: %w", ref, url, err)
+	}
+
+	if l.config.Resolved == nil {
+		l.config.Resolved = make(map[string]string)
+	}
+	l.config.Resolved[url+"@"+ref] = commit
+	return nil
+}
+
+// mergeStep adds script to hookName, replacing an earlier step of the same
+// name.
+func (l *configLoader) mergeStep(hookName string, script HookScript) {
+	if l.config.Hooks == nil {
+		l.config.Hooks = make(map[string][]HookScript)
+	}
+
+	steps := l.config.Hooks[hookName]
+	for i, step := range steps {
+		if step.Name == script.Name {
+			steps[i] = script
+			return
+		}
+	}
+	l.config.Hooks[hookName] = append(steps, script)
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigInclude(t *testing.T) {
+	tests := []struct {
+		name          string
+		files         map[string]string
+		expectedSteps string
+		expectError   bool
+		errorMsg      []string
+	}{
+		{
+			name: "included steps and settings are merged",
+			files: map[string]string{
+				"shared.yaml": `
+hooks:
+  pre-commit:
+    - name: "lint"
+      command: "golangci-lint run"
+      description: "Shared lint"
+    - name: "test"
+      command: "go test ./..."
+      description: "Shared test"
+settings:
+  timeout: "5m"
+  parallel_workers: 2
+`,
+				"hooky.yaml": `
+include: shared.yaml
+hooks:
+  pre-commit:
+    - name: "test"
+      command: "go test -short ./..."
+      description: "Overrides the shared test step"
+    - name: "extra"
+      command: "true"
+      description: "Local step"
+settings:
+  parallel_workers: 8
+`,
+			},
+			expectedSteps: "lint=golangci-lint run test=go test -short ./... extra=true",
+		},
+		{
+			name: "includes are relative to the including file",
+			files: map[string]string{
+				"shared/base.yaml": `
+extends: common/first.yaml
+include:
+  - common/second.yaml
+`,
+				"shared/common/first.yaml": `
+hooks:
+  pre-commit:
+    - name: "first"
+      command: "true"
+      description: "First"
+`,
+				"shared/common/second.yaml": `
+hooks:
+  pre-commit:
+    - name: "second"
+      command: "true"
+      description: "Second"
+`,
+				"hooky.yaml": `
+include:
+  - path: shared/base.yaml
+`,
+			},
+			expectedSteps: "first=true second=true",
+		},
+		{
+			name: "include cycle",
+			files: map[string]string{
+				"a.yaml":     "include: b.yaml\n",
+				"b.yaml":     "include: a.yaml\n",
+				"hooky.yaml": "include: a.yaml\n",
+			},
+			expectError: true,
+			errorMsg:    []string{"include cycle", "a.yaml -> ", "b.yaml -> "},
+		},
+		{
+			name: "invalid step names its file",
+			files: map[string]string{
+				"shared.yaml": `
+hooks:
+  pre-commit:
+    - name: "broken"
+      description: "Has neither script nor command"
+`,
+				"hooky.yaml": "include: shared.yaml\n",
+			},
+			expectError: true,
+			errorMsg:    []string{"shared.yaml (included from", "hooky.yaml)", "must specify either 'script' or 'command'"},
+		},
+		{
+			name: "invalid settings name their file",
+			files: map[string]string{
+				"shared.yaml": `
+settings:
+  timeout: "soon"
+`,
+				"hooky.yaml": "include: [shared.yaml]\n",
+			},
+			expectError: true,
+			errorMsg:    []string{"shared.yaml (included from", "settings.timeout"},
+		},
+		{
+			name: "missing include",
+			files: map[string]string{
+				"hooky.yaml": "include: missing.yaml\n",
+			},
+			expectError: true,
+			errorMsg:    []string{"missing.yaml (included from", "failed to read config file"},
+		},
+		{
+			name: "ref without repository",
+			files: map[string]string{
+				"hooky.yaml": `
+include:
+  - path: shared.yaml
+    ref: v1
+`,
+			},
+			expectError: true,
+			errorMsg:    []string{"needs a git repository"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, tmpDir, name, content)
+			}
+
+			config, err := LoadConfig(filepath.Join(tmpDir, "hooky.yaml"))
+
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("Expected error but got none")
+				}
+				for _, msg := range tt.errorMsg {
+					if !strings.Contains(err.Error(), msg) {
+						t.Errorf("Expected error to contain %q, got: %v", msg, err)
+					}
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+
+			var steps []string
+			for _, step := range config.Hooks["pre-commit"] {
+				steps = append(steps, step.Name+"="+step.CommandLine())
+			}
+			if got := strings.Join(steps, " "); got != tt.expectedSteps {
+				t.Errorf("Expected steps %q, got %q", tt.expectedSteps, got)
+			}
+		})
+	}
+}
+
+func TestLoadConfigIncludeSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, tmpDir, "shared.yaml", `
+settings:
+  timeout: "5m"
+  parallel_workers: 2
+`)
+	writeFile(t, tmpDir, "hooky.yaml", `
+include: shared.yaml
+settings:
+  parallel_workers: 8
+`)
+
+	config, err := LoadConfig(filepath.Join(tmpDir, "hooky.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if config.Settings.Timeout != "5m" {
+		t.Errorf("Expected the included timeout, got %q", config.Settings.Timeout)
+	}
+	if config.Settings.ParallelWorkers != 8 {
+		t.Errorf("Expected the including file to override parallel_workers, got %d", config.Settings.ParallelWorkers)
+	}
+	if config.Settings.BackupDirectory != ".hooky-backup" {
+		t.Errorf("Expected defaults for unset keys, got %q", config.Settings.BackupDirectory)
+	}
+}
+
+// setupSharedRepo creates a bare repository holding files in one commit
+// tagged v1, and returns its file:// URL. Files ending in .sh are executable.
+func setupSharedRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	workDir := t.TempDir()
+	gitRun(t, workDir, "init", "-q")
+	for name, content := range files {
+		writeFile(t, workDir, name, content)
+		if strings.HasSuffix(name, ".sh") {
+			if err := os.Chmod(filepath.Join(workDir, name), 0755); err != nil {
+				t.Fatalf("Failed to make %s executable: %v", name, err)
+			}
+		}
+	}
+	gitRun(t, workDir, "add", ".")
+	gitRun(t, workDir, "commit", "-q", "-m", "shared hooks")
+	gitRun(t, workDir, "tag", "v1")
+
+	bareDir := filepath.Join(t.TempDir(), "shared.git")
+	gitRun(t, workDir, "clone", "-q", "--bare", workDir, bareDir)
+	return "file://" + filepath.ToSlash(bareDir)
+}
+
+func TestLoadConfigGitInclude(t *testing.T) {
+	t.Setenv("HOOKY_CACHE_DIR", t.TempDir())
+
+	url := setupSharedRepo(t, map[string]string{
+		"hooky/shared.yaml": `
+include: common.yaml
+hooks:
+  pre-commit:
+    - name: "shared"
+      command: "true"
+      description: "From the shared repository"
+`,
+		"hooky/common.yaml": `
+hooks:
+  pre-commit:
+    - name: "common"
+      command: "true"
+      description: "Included relative to the shared file"
+`,
+	})
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "hooky.yaml")
+	writeFile(t, tmpDir, "hooky.yaml", `
+include:
+  - git: "`+url+`"
+    ref: v1
+    path: hooky/shared.yaml
+`)
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	steps := config.Hooks["pre-commit"]
+	if len(steps) != 2 || steps[0].Name != "common" || steps[1].Name != "shared" {
+		t.Fatalf("Expected steps from the shared repository, got %+v", steps)
+	}
+	if !strings.HasSuffix(steps[1].Source, "@v1:hooky/shared.yaml") {
+		t.Errorf("Expected the source to name the repository file, got %q", steps[1].Source)
+	}
+
+	// Once fetched, the include is read from the cache without the network
+	if err := os.RemoveAll(strings.TrimPrefix(url, "file://")); err != nil {
+		t.Fatalf("Failed to remove shared repository: %v", err)
+	}
+	if _, err := LoadConfig(configPath); err != nil {
+		t.Errorf("Expected the cached include to be used, got: %v", err)
+	}
+
+	if _, err := loadConfig(configPath, true); err == nil || !strings.Contains(err.Error(), "failed to fetch v1") {
+		t.Errorf("Expected refreshing from a missing repository to fail, got: %v", err)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestFullWorkflow(t *testing.T) {
@@ -133,11 +135,11 @@ settings:
 		}
 
 		hookContent := string(content)
-		if !strings.Contains(hookContent, "test-script.sh") {
-			t.Error("Hook should contain script path")
+		if !strings.Contains(hookContent, "run pre-commit") {
+			t.Error("Hook should delegate to hooky run")
 		}
-		if !strings.Contains(hookContent, "echo 'Running echo command'") {
-			t.Error("Hook should contain command")
+		if strings.Contains(hookContent, "test-script.sh") {
+			t.Error("Hook should not bake in configured steps")
 		}
 		if !strings.Contains(hookContent, "Generated by hooky") {
 			t.Error("Hook should contain hooky signature")
@@ -173,6 +175,28 @@ settings:
 		if !strings.Contains(outputStr, "Running echo command") {
 			t.Error("Hook output should contain command output")
 		}
+		if !strings.Contains(outputStr, "args: test-arg") {
+			t.Error("Hook arguments should be forwarded to scripts")
+		}
+	})
+
+	// Config edits are picked up without reinstalling
+	t.Run("execute hooks after config change", func(t *testing.T) {
+		edited := strings.Replace(configContent, "Pre-push validation", "Edited pre-push validation", 1)
+		if err := os.WriteFile(configPath, []byte(edited), 0644); err != nil {
+			t.Fatalf("Failed to rewrite config: %v", err)
+		}
+		defer os.WriteFile(configPath, []byte(configContent), 0644)
+
+		cmd := exec.Command("bash", filepath.Join(hooksDir, "pre-push"), "origin", "url")
+		cmd.Dir = tmpDir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Hook execution failed: %v\nOutput: %s", err, output)
+		}
+		if !strings.Contains(string(output), "Edited pre-push validation") {
+			t.Errorf("Hook should run the edited config, got: %s", output)
+		}
 	})
 
 	// Test --uninstall command
@@ -378,4 +402,224 @@ hooks:
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+// buildHooky compiles the hooky binary into dir and returns its path.
+func buildHooky(t *testing.T, dir string) string {
+	t.Helper()
+
+	srcDir, _ := os.Getwd()
+	hookyPath := filepath.Join(dir, "hooky")
+	buildCmd := exec.Command("go", "build", "-o", hookyPath)
+	buildCmd.Dir = srcDir
+	if output, err := buildCmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build hooky: %v\n%s", err, output)
+	}
+	return hookyPath
+}
+
+// exitCode runs cmd and returns its combined output and exit status.
+func exitCode(t *testing.T, cmd *exec.Cmd) (string, int) {
+	t.Helper()
+
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return string(output), 0
+	}
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		t.Fatalf("Failed to run %v: %v", cmd.Args, err)
+	}
+	return string(output), exitErr.ExitCode()
+}
+
+func TestSubcommands(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	tmpDir := t.TempDir()
+	hookyPath := buildHooky(t, t.TempDir())
+
+	initCmd := exec.Command("git", "init")
+	initCmd.Dir = tmpDir
+	if err := initCmd.Run(); err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+
+	configContent := `
+hooks:
+  pre-commit:
+    - name: "echo"
+      command: "echo subcommand-hook"
+      description: "Echo"
+    - name: "plan9"
+      command: "echo plan9-hook"
+      description: "Only on Plan 9"
+      if: 'os == "plan9"'
+  pre-push:
+    - name: "not-main"
+      command: "echo not-main-hook"
+      description: "Except for pushes to main"
+      if: '!pushed("refs/heads/main")'
+    - name: "origin"
+      command: "echo origin-hook"
+      description: "Only for pushes to origin"
+      if: 'remote == "origin"'
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "custom.yaml"), []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		contains     []string
+	}{
+		{"no command", nil, exitUsage, []string{"Usage: hooky"}},
+		{"unknown command", []string{"frobnicate"}, exitUsage, []string{"unknown command"}},
+		{"help for command", []string{"help", "run"}, exitOK, []string{"Usage: hooky run", "<hook>"}},
+		{"version", []string{"version"}, exitOK, []string{"hooky version"}},
+		{"legacy version flag", []string{"--version"}, exitOK, []string{"hooky version"}},
+		{"status before install", []string{"status", "--config", "custom.yaml"}, exitNotInstalled, []string{"pre-commit", "not installed"}},
+		{"install", []string{"install", "--config", "custom.yaml"}, exitOK, []string{"Hooks installed successfully"}},
+		{"status after install", []string{"--config", "custom.yaml", "status"}, exitOK, []string{"✅ installed"}},
+		{"list", []string{"list", "--config", "custom.yaml"}, exitOK, []string{"echo subcommand-hook"}},
+		{"list conditions", []string{"list", "--config", "custom.yaml"}, exitOK, []string{`if: os == "plan9" (would be skipped: os is`}},
+		{"list pushed conditions", []string{"list", "--config", "custom.yaml"}, exitOK, []string{`if: !pushed("refs/heads/main") (depends on the push)`, `if: remote == "origin" (depends on the push)`}},
+		{"run", []string{"--config", "custom.yaml", "run", "pre-commit"}, exitOK, []string{"subcommand-hook", "Skipped: plan9 (os is"}},
+		{"run unknown hook", []string{"run", "--config", "custom.yaml", "pre-nothing"}, exitUsage, []string{"unknown git hook"}},
+		{"run without hook", []string{"run"}, exitUsage, []string{"Usage: hooky run"}},
+		{"legacy flags combined", []string{"--install", "--list"}, exitUsage, []string{"cannot be combined"}},
+		{"legacy flag with command", []string{"--list", "install"}, exitUsage, []string{"cannot be combined"}},
+		{"legacy uninstall", []string{"--config", "custom.yaml", "--uninstall"}, exitOK, []string{"deprecated", "Hooks uninstalled successfully"}},
+		{"restore list without backups", []string{"restore", "--config", "custom.yaml", "--list"}, exitOK, []string{"No backups found"}},
+		{"restore unknown timestamp", []string{"restore", "--config", "custom.yaml", "--timestamp", "1234"}, exitError, []string{"no backups with timestamp 1234"}},
+		{"migrate dry run", []string{"migrate", "--config", "custom.yaml", "--dry-run"}, exitOK, []string{"from version 2 to 3", "version: 3"}},
+		{"migrate missing config", []string{"migrate", "--config", "missing.yaml"}, exitError, []string{"failed to read config file"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(hookyPath, tt.args...)
+			cmd.Dir = tmpDir
+			output, code := exitCode(t, cmd)
+
+			if code != tt.expectedCode {
+				t.Errorf("Expected exit code %d, got %d\nOutput: %s", tt.expectedCode, code, output)
+			}
+			for _, expected := range tt.contains {
+				if !strings.Contains(output, expected) {
+					t.Errorf("Expected output to contain '%s', got: %s", expected, output)
+				}
+			}
+		})
+	}
+}
+
+func TestStashRestoredOnInterrupt(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("Interrupt signals cannot be sent to processes on Windows")
+	}
+
+	hookyPath := buildHooky(t, t.TempDir())
+	root, _ := setupStashRepo(t)
+	writeFile(t, root, "hooky.yaml", `
+hooks:
+  pre-commit:
+    - name: "slow"
+      command: "touch ../started; sleep 30"
+      description: "Interrupted by the user"
+settings:
+  stash_unstaged: true
+`)
+
+	cmd := exec.Command(hookyPath, "run", "pre-commit")
+	cmd.Dir = root
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start hooky: %v", err)
+	}
+
+	started := filepath.Join(filepath.Dir(root), "started")
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(started); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if content := readFile(t, root, "f"); strings.Contains(content, "UNSTAGED") {
+		t.Fatal("Unstaged changes should be stashed while the step runs")
+	}
+
+	cmd.Process.Signal(os.Interrupt)
+	err := cmd.Wait()
+
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok || exitErr.ExitCode() != exitInterrupted {
+		t.Errorf("Expected exit code %d, got: %v", exitInterrupted, err)
+	}
+
+	if content := readFile(t, root, "f"); content != "STAGED\nl2\nl3\nl4\nUNSTAGED\n" {
+		t.Errorf("Unstaged changes should be restored after an interrupt, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(root, "newdir/untracked.txt")); err != nil {
+		t.Error("Untracked file should be restored after an interrupt")
+	}
+}
+
+func TestInstalledHookAfterMove(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	hookyPath := buildHooky(t, t.TempDir())
+	parent := t.TempDir()
+	repoDir := filepath.Join(parent, "repo")
+	gitRun(t, parent, "init", "-q", "repo")
+
+	writeFile(t, repoDir, "hooky.yaml", `
+hooks:
+  pre-commit:
+    - name: "record"
+      script: "hooks/record.sh"
+      description: "Records that the hook ran from the repository root"
+`)
+	writeFile(t, repoDir, "hooks/record.sh", "#!/bin/sh\necho ran >> .git/hook-ran\n")
+	if err := os.Chmod(filepath.Join(repoDir, "hooks/record.sh"), 0755); err != nil {
+		t.Fatalf("Failed to make script executable: %v", err)
+	}
+
+	// Install from a subdirectory, with the configuration given relative to it
+	subDir := filepath.Join(repoDir, "sub", "dir")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+	cmd := exec.Command(hookyPath, "install", "--config", "../../hooky.yaml")
+	cmd.Dir = subDir
+	if output, code := exitCode(t, cmd); code != exitOK {
+		t.Fatalf("Install from subdirectory failed with code %d: %s", code, output)
+	}
+
+	if content := readFile(t, repoDir, ".git/hooks/pre-commit"); strings.Contains(content, repoDir) {
+		t.Errorf("Hook should not contain the repository path, got:\n%s", content)
+	}
+
+	gitRun(t, subDir, "commit", "-q", "--allow-empty", "-m", "from subdirectory")
+	if content := readFile(t, repoDir, ".git/hook-ran"); content != "ran\n" {
+		t.Fatalf("Expected the hook to run once, got %q", content)
+	}
+
+	movedDir := filepath.Join(parent, "moved")
+	if err := os.Rename(repoDir, movedDir); err != nil {
+		t.Fatalf("Failed to move repository: %v", err)
+	}
+
+	gitRun(t, filepath.Join(movedDir, "sub", "dir"), "commit", "-q", "--allow-empty", "-m", "after move")
+	if content := readFile(t, movedDir, ".git/hook-ran"); content != "ran\nran\n" {
+		t.Errorf("Expected the hook to run again after moving the repository, got %q", content)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// expandVars replaces ${VAR} and ${VAR:-default} in s with the value lookup
+// returns for VAR; the default applies when VAR is unset or empty, and may
+// itself contain variables. An unset variable without a default expands to
+// nothing, or is an error when strict. $${ produces a literal ${, so shell
+// variables can be written as $${f}, and other ${...} forms are left for the
+// shell. Names starting with HOOKY_ are always left as written: hooky sets
+// them itself when a step runs.
+func expandVars(s string, strict bool, lookup func(string) (string, bool)) (string, error) {
+	var out strings.Builder
+	for {
+		i := strings.Index(s, "$")
+		if i < 0 {
+			out.WriteString(s)
+			return out.String(), nil
+		}
+		out.WriteString(s[:i])
+		s = s[i:]
+
+		if strings.HasPrefix(s, "$${") {
+			out.WriteString("${")
+			s = s[3:]
+			continue
+		}
+
+		name, def, hasDefault, rest, ok := parseVar(s)
+		if !ok {
+			out.WriteString("$")
+			s = s[1:]
+			continue
+		}
+		ref := s[:len(s)-len(rest)]
+		s = rest
+		if strings.HasPrefix(name, "HOOKY_") {
+			out.WriteString(ref)
+			continue
+		}
+
+		value, set := lookup(name)
+		switch {
+		case hasDefault && value == "":
+			expanded, err := expandVars(def, strict, lookup)
+			if err != nil {
+				return "", err
+			}
+			value = expanded
+		case !set && strict:
+			return "", fmt.Errorf("variable %s is not set", name)
+		}
+		out.WriteString(value)
+	}
+}
+
+// parseVar parses a ${VAR} or ${VAR:-default} reference at the start of s
+// and returns the rest of s after it.
+func parseVar(s string) (name, def string, hasDefault bool, rest string, ok bool) {
+	if !strings.HasPrefix(s, "${") {
+		return "", "", false, "", false
+	}
+	end := 2
+	for end < len(s) && (s[end] == '_' || isAlpha(s[end]) || (end > 2 && s[end] >= '0' && s[end] <= '9')) {
+		end++
+	}
+	name = s[2:end]
+	if name == "" {
+		return "", "", false, "", false
+	}
+
+	switch {
+	case strings.HasPrefix(s[end:], "}"):
+		return name, "", false, s[end+1:], true
+	case strings.HasPrefix(s[end:], ":-"):
+		// The default runs to the matching brace, so it may hold ${...}
+		depth := 1
+		for i := end + 2; i < len(s); i++ {
+			switch {
+			case strings.HasPrefix(s[i:], "${"):
+				depth++
+				i++
+			case s[i] == '}':
+				depth--
+				if depth == 0 {
+					return name, s[end+2 : i], true, s[i+1:], true
+				}
+			}
+		}
+	}
+	return "", "", false, "", false
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// expandSettings expands variables in the values of a settings node before
+// it is decoded onto config.Settings, and records the raw values that
+// contained any. Unquoted values are typed after expansion, so
+// parallel_workers: ${WORKERS:-4} is a number.
+func expandSettings(config *Config, settings *yaml.Node) error {
+	if settings.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	// Strict mode may be switched on by the same settings block
+	if node := mappingValue(settings, "strict_variables"); node != nil {
+		if err := node.Decode(&config.Settings.StrictVariables); err != nil {
+			return fmt.Errorf("settings.strict_variables: %w", err)
+		}
+	}
+
+	for i := 0; i+1 < len(settings.Content); i += 2 {
+		key, value := settings.Content[i].Value, settings.Content[i+1]
+		delete(config.RawSettings, key)
+		if key == "env" && value.Kind == yaml.MappingNode {
+			for j := 1; j < len(value.Content); j += 2 {
+				expanded, err := expandVars(value.Content[j].Value, config.Settings.StrictVariables, os.LookupEnv)
+				if err != nil {
+					return fmt.Errorf("settings.env.%s: %w", value.Content[j-1].Value, err)
+				}
+				value.Content[j].Value = expanded
+			}
+			continue
+		}
+		if value.Kind != yaml.ScalarNode || value.ShortTag() != "!!str" {
+			continue
+		}
+
+		expanded, err := expandVars(value.Value, config.Settings.StrictVariables, os.LookupEnv)
+		if err != nil {
+			return fmt.Errorf("settings.%s: %w", key, err)
+		}
+		if expanded == value.Value {
+			continue
+		}
+
+		if config.RawSettings == nil {
+			config.RawSettings = make(map[string]string)
+		}
+		config.RawSettings[key] = value.Value
+		value.Value = expanded
+		if value.Style == 0 {
+			value.Tag = ""
+		}
+	}
+	return nil
+}
+
+// expandSteps expands variables in the script, command and env values of
+// every step, keeping the line as written in Raw. Script and command lines
+// see the variables the step runs with, so a step's env overrides hooky's
+// environment there.
+func expandSteps(config *Config) error {
+	for hookName, scripts := range config.Hooks {
+		for i := range scripts {
+			script := &scripts[i]
+			if err := expandEnv(script.Env, config.Settings.StrictVariables); err != nil {
+				return fmt.Errorf("hook %s[%d] (%s): %w", hookName, i, script.Name, err)
+			}
+
+			// An env file that cannot be read fails the step when it runs
+			vars, _ := mergeStepVars(config.Settings, *script)
+			lookup := func(name string) (string, bool) {
+				if value, ok := vars[name]; ok {
+					return value, true
+				}
+				return os.LookupEnv(name)
+			}
+
+			for _, line := range []*string{&script.Script, &script.Command} {
+				expanded, err := expandVars(*line, config.Settings.StrictVariables, lookup)
+				if err != nil {
+					return fmt.Errorf("hook %s[%d] (%s): %w", hookName, i, script.Name, err)
+				}
+				if expanded != *line {
+					script.Raw = *line
+					*line = expanded
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// expandEnv expands variables in the values of env in place.
+func expandEnv(env map[string]string, strict bool) error {
+	for name, value := range env {
+		expanded, err := expandVars(value, strict, os.LookupEnv)
+		if err != nil {
+			return fmt.Errorf("env.%s: %w", name, err)
+		}
+		env[name] = expanded
+	}
+	return nil
+}
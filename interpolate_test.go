@@ -0,0 +1,255 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandVars(t *testing.T) {
+	t.Setenv("INTERP_TEST_TAGS", "integration")
+	t.Setenv("INTERP_TEST_EMPTY", "")
+
+	tests := []struct {
+		input       string
+		strict      bool
+		expected    string
+		expectError string
+	}{
+		{input: "go test ./...", expected: "go test ./..."},
+		{input: "go test -tags ${INTERP_TEST_TAGS} ./...", expected: "go test -tags integration ./..."},
+		{input: "go test -tags ${INTERP_TEST_TAGS:-unit} ./...", expected: "go test -tags integration ./..."},
+		{input: "go test -tags ${INTERP_TEST_UNSET:-unit} ./...", expected: "go test -tags unit ./..."},
+		{input: "${INTERP_TEST_EMPTY:-default}", expected: "default"},
+		{input: "${INTERP_TEST_UNSET:-${INTERP_TEST_TAGS}-suffix}", expected: "integration-suffix"},
+		{input: "${INTERP_TEST_UNSET:-}", strict: true, expected: ""},
+		{input: "a${INTERP_TEST_UNSET}b", expected: "ab"},
+		{input: "a${INTERP_TEST_UNSET}b", strict: true, expectError: "variable INTERP_TEST_UNSET is not set"},
+		{input: "${INTERP_TEST_EMPTY}", strict: true, expected: ""},
+		{input: "sort ${HOOKY_STAGED_FILES}", strict: true, expected: "sort ${HOOKY_STAGED_FILES}"},
+		{input: "${HOOKY_HOOK:-none}", expected: "${HOOKY_HOOK:-none}"},
+		{input: "echo $${INTERP_TEST_TAGS}", expected: "echo ${INTERP_TEST_TAGS}"},
+		{input: "echo $HOME $$ ${#x} ${x%%y} ${1}", strict: true, expected: "echo $HOME $$ ${#x} ${x%%y} ${1}"},
+		{input: "unterminated ${INTERP_TEST_TAGS:-x", expected: "unterminated ${INTERP_TEST_TAGS:-x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := expandVars(tt.input, tt.strict, os.LookupEnv)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("Expected error containing %q, got %q, %v", tt.expectError, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandVars failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expandVars(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadConfigInterpolation(t *testing.T) {
+	t.Setenv("INTERP_TEST_TAGS", "integration")
+	t.Setenv("INTERP_TEST_WORKERS", "6")
+
+	tests := []struct {
+		name        string
+		files       map[string]string
+		command     string
+		raw         string
+		workers     int
+		rawSettings map[string]string
+		errorMsg    string
+	}{
+		{
+			name: "steps and settings are expanded",
+			files: map[string]string{
+				"hooky.yaml": `
+hooks:
+  pre-commit:
+    - name: "test"
+      command: "go test -tags ${INTERP_TEST_TAGS:-unit} ./..."
+settings:
+  parallel_workers: ${INTERP_TEST_WORKERS}
+  timeout: "${INTERP_TEST_TIMEOUT:-5m}"
+`,
+			},
+			command:     "go test -tags integration ./...",
+			raw:         "go test -tags ${INTERP_TEST_TAGS:-unit} ./...",
+			workers:     6,
+			rawSettings: map[string]string{"parallel_workers": "${INTERP_TEST_WORKERS}", "timeout": "${INTERP_TEST_TIMEOUT:-5m}"},
+		},
+		{
+			name: "a later file without variables replaces the raw value",
+			files: map[string]string{
+				"shared.yaml": `
+settings:
+  parallel_workers: ${INTERP_TEST_WORKERS}
+`,
+				"hooky.yaml": `
+include: shared.yaml
+hooks:
+  pre-commit:
+    - name: "test"
+      command: "go test ./..."
+settings:
+  parallel_workers: 2
+`,
+			},
+			command: "go test ./...",
+			workers: 2,
+		},
+		{
+			name: "step and settings env override the environment",
+			files: map[string]string{
+				"hooky.yaml": `
+hooks:
+  pre-commit:
+    - name: "test"
+      command: "go test -tags ${INTERP_TEST_TAGS:-unit} ${INTERP_TEST_PKG}"
+      env:
+        INTERP_TEST_TAGS: e2e
+settings:
+  env:
+    INTERP_TEST_PKG: ./cmd/...
+`,
+			},
+			command: "go test -tags e2e ./cmd/...",
+			raw:     "go test -tags ${INTERP_TEST_TAGS:-unit} ${INTERP_TEST_PKG}",
+		},
+		{
+			name: "escaped variables are left for the shell",
+			files: map[string]string{
+				"hooky.yaml": `
+hooks:
+  pre-commit:
+    - name: "test"
+      command: "for d in cmd internal; do go test ./$${d}/...; done"
+settings:
+  strict_variables: true
+`,
+			},
+			command: "for d in cmd internal; do go test ./${d}/...; done",
+			raw:     "for d in cmd internal; do go test ./$${d}/...; done",
+		},
+		{
+			name: "strict mode rejects unset variables in commands",
+			files: map[string]string{
+				"hooky.yaml": `
+hooks:
+  pre-commit:
+    - name: "test"
+      command: "echo tags=${INTERP_TEST_UNSET}"
+settings:
+  strict_variables: true
+`,
+			},
+			errorMsg: "hook pre-commit[0] (test): variable INTERP_TEST_UNSET is not set",
+		},
+		{
+			name: "strict mode rejects unset variables in step env",
+			files: map[string]string{
+				"hooky.yaml": `
+hooks:
+  pre-commit:
+    - name: "test"
+      command: "go test ./..."
+      env:
+        TAGS: "${INTERP_TEST_UNSET}"
+settings:
+  strict_variables: true
+`,
+			},
+			errorMsg: "hook pre-commit[0] (test): env.TAGS: variable INTERP_TEST_UNSET is not set",
+		},
+		{
+			name: "strict mode rejects unset variables in settings",
+			files: map[string]string{
+				"hooky.yaml": `
+settings:
+  timeout: ${INTERP_TEST_UNSET}
+  strict_variables: true
+`,
+			},
+			errorMsg: "settings.timeout: variable INTERP_TEST_UNSET is not set",
+		},
+		{
+			name: "strict mode from the local override",
+			files: map[string]string{
+				"hooky.yaml": `
+hooks:
+  pre-commit:
+    - name: "test"
+      script: "hooks/test.sh"
+      env:
+        DIR: "${INTERP_TEST_UNSET}"
+`,
+				"hooky.local.yaml": `
+settings:
+  strict_variables: true
+`,
+			},
+			errorMsg: "variable INTERP_TEST_UNSET is not set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, tmpDir, name, content)
+			}
+
+			config, err := LoadConfig(filepath.Join(tmpDir, "hooky.yaml"))
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got: %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+
+			step := config.Hooks["pre-commit"][0]
+			if step.Command != tt.command || step.Raw != tt.raw {
+				t.Errorf("Expected command %q (raw %q), got %q (raw %q)", tt.command, tt.raw, step.Command, step.Raw)
+			}
+			if config.Settings.ParallelWorkers != tt.workers {
+				t.Errorf("Expected parallel_workers %d, got %d", tt.workers, config.Settings.ParallelWorkers)
+			}
+			if len(config.RawSettings) != len(tt.rawSettings) {
+				t.Errorf("Expected raw settings %v, got %v", tt.rawSettings, config.RawSettings)
+			}
+			for key, raw := range tt.rawSettings {
+				if config.RawSettings[key] != raw {
+					t.Errorf("Expected raw %s %q, got %q", key, raw, config.RawSettings[key])
+				}
+			}
+		})
+	}
+}
+
+func TestShellVariablesInCommands(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "loop"
+      command: 'for f in a b; do echo "file=$${f}" >> loop.txt; done; true'
+      description: "Uses a shell loop variable"
+settings:
+  strict_variables: true
+`)
+
+	if err := NewHookManager("hooky.yaml", false).RunHook("pre-commit", nil); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+	if content := readFile(t, tmpDir, "loop.txt"); content != "file=a\nfile=b\n" {
+		t.Errorf("Expected the shell to expand $${f}, got %q", content)
+	}
+}
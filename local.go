@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// localConfigPath returns the path of the local override of configPath:
+// hooky.local.yaml next to hooky.yaml.
+func localConfigPath(configPath string) string {
+	ext := filepath.Ext(configPath)
+	return strings.TrimSuffix(configPath, ext) + ".local" + ext
+}
+
+// localConfigFile is the on-disk form of a local override file. Steps are
+// kept as nodes so that a step only overrides the fields it sets.
+type localConfigFile struct {
+	Hooks    map[string][]yaml.Node `yaml:"hooks"`
+	Settings yaml.Node              `yaml:"settings"`
+}
+
+// applyLocalConfig layers the local override file at path, if there is
+// one, on top of config. A step with the name of an existing step of the
+// same hook overrides the fields it sets, and disabled: true turns the step
+// off; any other step is added. Settings override the shared ones key by
+// key.
+func applyLocalConfig(config *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: failed to read config file: %w", path, err)
+	}
+
+	var file localConfigFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%s: failed to parse config file: %w", path, err)
+	}
+
+	for hookName, nodes := range file.Hooks {
+		for _, node := range nodes {
+			if err := applyLocalStep(config, hookName, &node, path); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+	if err := validateHookScripts(config.Hooks); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if !file.Settings.IsZero() {
+		if err := expandSettings(config, &file.Settings); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := file.Settings.Decode(&config.Settings); err != nil {
+			return fmt.Errorf("%s: failed to parse config file: %w", path, err)
+		}
+		if err := validateSettings(config.Settings); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	config.Files = append(config.Files, path)
+	return nil
+}
+
+func applyLocalStep(config *Config, hookName string, node *yaml.Node, source string) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: hook %s: steps must be mappings", node.Line, hookName)
+	}
+	var keys []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if key := node.Content[i].Value; key != "name" {
+			keys = append(keys, key)
+		}
+	}
+
+	var ref struct {
+		Name     string `yaml:"name"`
+		Disabled bool   `yaml:"disabled"`
+	}
+	if err := node.Decode(&ref); err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	if ref.Name == "" {
+		return fmt.Errorf("line %d: hook %s: local steps need a name", node.Line, hookName)
+	}
+
+	if config.Hooks == nil {
+		config.Hooks = make(map[string][]HookScript)
+	}
+	steps := config.Hooks[hookName]
+	for i, step := range steps {
+		if step.Name != ref.Name {
+			continue
+		}
+		// Replacing what the step runs replaces both ways of saying it
+		if mappingValue(node, "script") != nil || mappingValue(node, "command") != nil {
+			step.Script, step.Command = "", ""
+		}
+		// env adds to the shared step's variables; copy them so the shared
+		// map is not modified
+		step.Env = maps.Clone(step.Env)
+		if err := node.Decode(&step); err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		step.Overridden = append(step.Overridden, keys...)
+		steps[i] = step
+		return nil
+	}
+
+	if ref.Disabled {
+		return fmt.Errorf("line %d: hook %s has no step named %q to disable", node.Line, hookName, ref.Name)
+	}
+
+	var step HookScript
+	if err := node.Decode(&step); err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	step.Source = source
+	step.Local = true
+	config.Hooks[hookName] = append(steps, step)
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalConfig(t *testing.T) {
+	sharedYAML := `
+hooks:
+  pre-commit:
+    - name: "lint"
+      command: "golangci-lint run"
+      description: "Lint"
+      timeout: "5m"
+    - name: "test"
+      script: "hooks/test.sh"
+      description: "Slow tests"
+settings:
+  timeout: "10m"
+`
+
+	tests := []struct {
+		name          string
+		localYAML     string
+		expectedSteps string
+		disabled      string
+		overridden    map[string]string
+		errorMsg      string
+	}{
+		{
+			name:          "no local file",
+			expectedSteps: "lint=golangci-lint run test=hooks/test.sh",
+		},
+		{
+			name: "disable a step",
+			localYAML: `
+hooks:
+  pre-commit:
+    - name: "test"
+      disabled: true
+`,
+			expectedSteps: "lint=golangci-lint run",
+			disabled:      "test",
+			overridden:    map[string]string{"test": "disabled"},
+		},
+		{
+			name: "override fields and add a step",
+			localYAML: `
+hooks:
+  pre-commit:
+    - name: "lint"
+      timeout: "1m"
+    - name: "test"
+      command: "go test -short ./..."
+    - name: "mine"
+      command: "echo mine"
+  pre-push:
+    - name: "push"
+      command: "echo push"
+`,
+			expectedSteps: "lint=golangci-lint run test=go test -short ./... mine=echo mine",
+			overridden:    map[string]string{"lint": "timeout", "test": "command"},
+		},
+		{
+			name: "disable an unknown step",
+			localYAML: `
+hooks:
+  pre-commit:
+    - name: "typo"
+      disabled: true
+`,
+			errorMsg: `hooky.local.yaml: line 4: hook pre-commit has no step named "typo" to disable`,
+		},
+		{
+			name: "step without a name",
+			localYAML: `
+hooks:
+  pre-commit:
+    - command: "echo"
+`,
+			errorMsg: "local steps need a name",
+		},
+		{
+			name: "invalid new step",
+			localYAML: `
+hooks:
+  pre-commit:
+    - name: "mine"
+      description: "Neither script nor command"
+`,
+			errorMsg: "hooky.local.yaml: hook pre-commit[2] (mine): must specify either 'script' or 'command'",
+		},
+		{
+			name: "invalid settings",
+			localYAML: `
+settings:
+  lockfile: maybe
+`,
+			errorMsg: "hooky.local.yaml: settings.lockfile must be",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			writeFile(t, tmpDir, "hooky.yaml", sharedYAML)
+			if tt.localYAML != "" {
+				writeFile(t, tmpDir, "hooky.local.yaml", tt.localYAML)
+			}
+
+			config, err := LoadConfig(filepath.Join(tmpDir, "hooky.yaml"))
+
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got: %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+
+			var steps []string
+			for _, step := range config.Hooks["pre-commit"] {
+				steps = append(steps, step.Name+"="+step.CommandLine())
+			}
+			if got := strings.Join(steps, " "); got != tt.expectedSteps {
+				t.Errorf("Expected steps %q, got %q", tt.expectedSteps, got)
+			}
+
+			var disabled []string
+			for _, step := range config.Disabled["pre-commit"] {
+				disabled = append(disabled, step.Name)
+			}
+			if got := strings.Join(disabled, " "); got != tt.disabled {
+				t.Errorf("Expected disabled steps %q, got %q", tt.disabled, got)
+			}
+
+			for _, step := range append(config.Hooks["pre-commit"], config.Disabled["pre-commit"]...) {
+				if got := strings.Join(step.Overridden, ", "); got != tt.overridden[step.Name] {
+					t.Errorf("Expected %s to have overridden fields %q, got %q", step.Name, tt.overridden[step.Name], got)
+				}
+				if step.Name == "mine" && (!step.Local || !strings.HasSuffix(step.Source, "hooky.local.yaml")) {
+					t.Errorf("Expected the added step to come from hooky.local.yaml, got %+v", step)
+				}
+			}
+
+			if config.Settings.Timeout != "10m" {
+				t.Errorf("Expected shared settings to be kept, got timeout %q", config.Settings.Timeout)
+			}
+		})
+	}
+}
+
+func TestLocalConfigPath(t *testing.T) {
+	tests := map[string]string{
+		"hooky.yaml":         "hooky.local.yaml",
+		"ci/custom.yml":      "ci/custom.local.yml",
+		"/repo/hooky-config": "/repo/hooky-config.local",
+	}
+	for configPath, expected := range tests {
+		if got := localConfigPath(configPath); got != expected {
+			t.Errorf("localConfigPath(%q) = %q, expected %q", configPath, got, expected)
+		}
+	}
+}
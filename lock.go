@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// lockVersion is the format version of lock files.
+const lockVersion = 1
+
+const lockHeader = "# Generated by 'hooky install'. Commit this file; 'hooky run' checks the\n# hook scripts and shared configuration against it.\n"
+
+// hookLock is the content of the lock file: the commit each source fetched
+// from a git repository resolved to, and the content hash of each script
+// file, as of the last install.
+type hookLock struct {
+	Version int               `yaml:"version"`
+	Sources map[string]string `yaml:"sources,omitempty"`
+	Scripts map[string]string `yaml:"scripts,omitempty"`
+}
+
+// lockPath returns the lock file of the configuration: hooky.lock next to
+// hooky.yaml.
+func (hm *HookManager) lockPath() string {
+	return strings.TrimSuffix(hm.configPath, filepath.Ext(hm.configPath)) + ".lock"
+}
+
+// buildLock computes the lock for the current configuration.
+func (hm *HookManager) buildLock() (*hookLock, error) {
+	lock := &hookLock{Version: lockVersion, Sources: hm.config.Resolved, Scripts: map[string]string{}}
+	for _, hooks := range []map[string][]HookScript{hm.config.Hooks, hm.config.Disabled} {
+		for _, scripts := range hooks {
+			for _, script := range scripts {
+				if !lockedStep(script) {
+					continue
+				}
+				key, sum, err := scriptChecksum(script)
+				if err != nil {
+					return nil, err
+				}
+				if key != "" {
+					lock.Scripts[key] = sum
+				}
+			}
+		}
+	}
+	return lock, nil
+}
+
+// writeLock records the current configuration in the lock file.
+func (hm *HookManager) writeLock() error {
+	lock, err := hm.buildLock()
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.NewBufferString(lockHeader)
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(lock); err != nil {
+		return fmt.Errorf("failed to write %s: %w", hm.lockPath(), err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", hm.lockPath(), err)
+	}
+	if err := os.WriteFile(hm.lockPath(), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", hm.lockPath(), err)
+	}
+
+	if hm.config.Settings.Verbose {
+		fmt.Printf("Wrote %s\n", hm.lockPath())
+	}
+	return nil
+}
+
+// readLock reads the lock file, returning nil if there is none.
+func (hm *HookManager) readLock() (*hookLock, error) {
+	data, err := os.ReadFile(hm.lockPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", hm.lockPath(), err)
+	}
+
+	var lock hookLock
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", hm.lockPath(), err)
+	}
+	if lock.Version != lockVersion {
+		return nil, fmt.Errorf("%s has unsupported version %d, run 'hooky install' to regenerate it", hm.lockPath(), lock.Version)
+	}
+	return &lock, nil
+}
+
+// lockMismatches compares the sources of the configuration and the script
+// files of scripts with the lock file, and describes every difference.
+// Without a lock file there is nothing to compare.
+func (hm *HookManager) lockMismatches(scripts []HookScript) ([]string, error) {
+	lock, err := hm.readLock()
+	if err != nil || lock == nil {
+		return nil, err
+	}
+
+	var mismatches []string
+	for source, commit := range hm.config.Resolved {
+		locked, ok := lock.Sources[source]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("%s is not locked", source))
+		case locked != commit:
+			mismatches = append(mismatches, fmt.Sprintf("%s is at %s, locked at %s", source, shortCommit(commit), shortCommit(locked)))
+		}
+	}
+	sort.Strings(mismatches)
+
+	for _, script := range scripts {
+		if !lockedStep(script) {
+			continue
+		}
+		key, sum, err := scriptChecksum(script)
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			continue
+		}
+		locked, ok := lock.Scripts[key]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("script %s (from: %s) is not locked", key, script.Name))
+		case locked != sum:
+			mismatches = append(mismatches, fmt.Sprintf("script %s (from: %s) has changed", key, script.Name))
+		}
+	}
+
+	return mismatches, nil
+}
+
+// checkLock verifies the steps of a hook against the lock file before they
+// run. Depending on the lockfile setting, differences are reported as a
+// warning or stop the hook.
+func (hm *HookManager) checkLock(scripts []HookScript) error {
+	if hm.config.Settings.Lockfile == "off" {
+		return nil
+	}
+
+	mismatches, err := hm.lockMismatches(scripts)
+	if err != nil {
+		return err
+	}
+	if len(mismatches) == 0 {
+		return nil
+	}
+
+	message := fmt.Sprintf("%s does not match:\n  %s\nIf the changes are intended, run 'hooky install' to update it",
+		filepath.Base(hm.lockPath()), strings.Join(mismatches, "\n  "))
+	if hm.config.Settings.Lockfile == "strict" {
+		return fmt.Errorf("%s", message)
+	}
+	fmt.Fprintf(os.Stderr, "⚠️  %s\n", message)
+	return nil
+}
+
+// lockedStep reports whether the script a step runs is part of the shared
+// configuration. Steps that hooky.local.yaml adds, or points at another
+// script, differ between developers and are not locked.
+func lockedStep(script HookScript) bool {
+	if script.Local {
+		return false
+	}
+	for _, field := range script.Overridden {
+		if field == "script" || field == "working_dir" {
+			return false
+		}
+	}
+	return true
+}
+
+// scriptChecksum returns the lock key and content hash of the file a script
+// step runs. The key is empty for commands, for missing files and for
+// scripts from remote hook repositories, which are locked by the commit of
+// their source.
+func scriptChecksum(script HookScript) (string, string, error) {
+	if script.Script == "" {
+		return "", "", nil
+	}
+	file, ok := scriptFile(script)
+	if !ok {
+		return "", "", nil
+	}
+
+	path := stepPath(script, file)
+	if cacheDir, err := hookyCacheDir(); err == nil && isUnder(path, cacheDir) {
+		return "", "", nil
+	}
+
+	key := filepath.ToSlash(path)
+	if root, err := repoRoot(); err == nil && isUnder(path, root) {
+		rel, _ := filepath.Rel(root, path)
+		key = filepath.ToSlash(rel)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read script %s: %w", file, err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", "", fmt.Errorf("failed to read script %s: %w", file, err)
+	}
+	return key, "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// isUnder reports whether path is dir or inside it.
+func isUnder(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
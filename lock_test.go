@@ -0,0 +1,178 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLockfile(t *testing.T) {
+	tests := []struct {
+		name        string
+		lockfile    string
+		change      func(t *testing.T, dir string)
+		expectError string
+	}{
+		{
+			name:     "unchanged scripts",
+			lockfile: "strict",
+		},
+		{
+			name:     "changed script warns",
+			lockfile: "warn",
+			change: func(t *testing.T, dir string) {
+				writeFile(t, dir, "hooks/check.sh", "#!/bin/sh\nexit 0\n")
+			},
+		},
+		{
+			name:     "changed script refuses in strict mode",
+			lockfile: "strict",
+			change: func(t *testing.T, dir string) {
+				writeFile(t, dir, "hooks/check.sh", "#!/bin/sh\nexit 0\n")
+			},
+			expectError: "script hooks/check.sh (from: check) has changed",
+		},
+		{
+			name:     "new script refuses in strict mode",
+			lockfile: "strict",
+			change: func(t *testing.T, dir string) {
+				writeFile(t, dir, "hooks/other.sh", "#!/bin/sh\n")
+				config := readFile(t, dir, "hooky.yaml")
+				config = strings.Replace(config, "hooks/check.sh", "hooks/other.sh", 1)
+				writeFile(t, dir, "hooky.yaml", config)
+			},
+			expectError: "script hooks/other.sh (from: check) is not locked",
+		},
+		{
+			name:     "local steps are not locked",
+			lockfile: "strict",
+			change: func(t *testing.T, dir string) {
+				writeFile(t, dir, "hooks/mine.sh", "#!/bin/sh\n")
+				os.Chmod(filepath.Join(dir, "hooks/mine.sh"), 0755)
+				writeFile(t, dir, "hooky.local.yaml", `
+hooks:
+  pre-commit:
+    - name: "check"
+      disabled: true
+    - name: "mine"
+      script: "hooks/mine.sh"
+`)
+			},
+		},
+		{
+			name:     "missing lock file",
+			lockfile: "strict",
+			change: func(t *testing.T, dir string) {
+				os.Remove(filepath.Join(dir, "hooky.lock"))
+			},
+		},
+		{
+			name:     "checks disabled",
+			lockfile: "off",
+			change: func(t *testing.T, dir string) {
+				writeFile(t, dir, "hooks/check.sh", "#!/bin/sh\nexit 0\n")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "check"
+      script: "hooks/check.sh"
+      description: "Check"
+    - name: "echo"
+      command: "echo checked"
+      description: "A command is not locked"
+settings:
+  lockfile: `+tt.lockfile+`
+`)
+			writeFile(t, tmpDir, "hooks/check.sh", "#!/bin/sh\necho checking\n")
+			os.Chmod(filepath.Join(tmpDir, "hooks/check.sh"), 0755)
+
+			if err := NewHookManager("hooky.yaml", false).InstallHooks(); err != nil {
+				t.Fatalf("InstallHooks failed: %v", err)
+			}
+
+			_, err := os.Stat(filepath.Join(tmpDir, "hooky.lock"))
+			if tt.lockfile == "off" {
+				if err == nil {
+					t.Errorf("Expected no lock file with lockfile: off")
+				}
+			} else if content := readFile(t, tmpDir, "hooky.lock"); !strings.Contains(content, "hooks/check.sh: sha256:") || strings.Contains(content, "echo") {
+				t.Errorf("Expected the lock file to hold the script's checksum only, got:\n%s", content)
+			}
+
+			if tt.change != nil {
+				tt.change(t, tmpDir)
+			}
+
+			err = NewHookManager("hooky.yaml", false).RunHook("pre-commit", nil)
+			if tt.expectError == "" {
+				if err != nil {
+					t.Errorf("RunHook failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+				t.Errorf("Expected error containing %q, got: %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestLockfileSources(t *testing.T) {
+	t.Setenv("HOOKY_CACHE_DIR", t.TempDir())
+
+	url := setupSharedRepo(t, map[string]string{
+		"hooky-hooks.yaml": `
+hooks:
+  - name: "check"
+    script: "bin/check.sh"
+    description: "Remote check"
+`,
+		"bin/check.sh": "#!/bin/sh\n",
+	})
+
+	tmpDir := setupRunRepo(t, `
+repos:
+  - repo: "`+url+`"
+    rev: v1
+    hooks:
+      pre-commit:
+        - name: "check"
+settings:
+  lockfile: strict
+`)
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.InstallHooks(); err != nil {
+		t.Fatalf("InstallHooks failed: %v", err)
+	}
+
+	commit := hm.config.Resolved[url+"@v1"]
+	if len(commit) != 40 {
+		t.Fatalf("Expected the remote repository's commit to be resolved, got %v", hm.config.Resolved)
+	}
+
+	content := readFile(t, tmpDir, "hooky.lock")
+	if !strings.Contains(content, url+"@v1: "+commit) {
+		t.Errorf("Expected the lock file to pin the remote repository, got:\n%s", content)
+	}
+	if strings.Contains(content, "check.sh") {
+		t.Errorf("Expected remote scripts to be locked by their commit only, got:\n%s", content)
+	}
+
+	if err := NewHookManager("hooky.yaml", false).RunHook("pre-commit", nil); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+
+	writeFile(t, tmpDir, "hooky.lock", strings.Replace(content, commit, strings.Repeat("0", 40), 1))
+	err := NewHookManager("hooky.yaml", false).RunHook("pre-commit", nil)
+	if err == nil || !strings.Contains(err.Error(), "locked at 000000000000") {
+		t.Errorf("Expected a different commit to be refused, got: %v", err)
+	}
+}
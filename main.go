@@ -1,53 +1,375 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 )
 
 const version = "1.3.0"
 
+// Exit codes returned by hooky commands.
+const (
+	exitOK           = 0
+	exitError        = 1
+	exitUsage        = 2
+	exitNotInstalled = 3
+	exitTimeout      = 124
+	exitInterrupted  = 130
+)
+
+// globalOptions holds flags that may appear before the subcommand. Each
+// subcommand also accepts them so `hooky install --config x.yaml` works.
+type globalOptions struct {
+	configFile string
+	verbose    bool
+}
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	flags   func(fs *flag.FlagSet)
+	run     func(fs *flag.FlagSet, opts globalOptions) int
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{
+			name:    "install",
+			summary: "Install hooks from the configuration",
+			run:     runInstall,
+		},
+		{
+			name:    "uninstall",
+			summary: "Remove hooky-generated hooks",
+			flags: func(fs *flag.FlagSet) {
+				fs.Bool("restore", false, "Put back the newest backup of each removed hook")
+			},
+			run: runUninstall,
+		},
+		{
+			name:    "restore",
+			summary: "Restore hooks that hooky backed up during install",
+			flags: func(fs *flag.FlagSet) {
+				fs.Bool("list", false, "List available backups instead of restoring")
+				fs.Int64("timestamp", 0, "Restore the backups taken at this Unix timestamp instead of the newest")
+			},
+			run: runRestore,
+		},
+		{
+			name:    "list",
+			summary: "List configured hooks and validate their scripts",
+			run:     runList,
+		},
+		{
+			name:    "migrate",
+			summary: "Upgrade the configuration file to the current format",
+			flags: func(fs *flag.FlagSet) {
+				fs.Bool("dry-run", false, "Print the migrated configuration instead of writing it")
+			},
+			run: runMigrate,
+		},
+		{
+			name:    "run",
+			args:    "<hook> [args...]",
+			summary: "Run the steps configured for a hook",
+			run:     runRun,
+		},
+		{
+			name:    "status",
+			summary: "Show which configured hooks are installed",
+			run:     runStatus,
+		},
+		{
+			name:    "version",
+			summary: "Show version information",
+			run:     runVersion,
+		},
+		{
+			name:    "help",
+			args:    "[command]",
+			summary: "Show help for a command",
+			run:     runHelp,
+		},
+	}
+}
+
 func main() {
-	var (
-		configFile = flag.String("config", "hooky.yaml", "Path to configuration file")
-		install    = flag.Bool("install", false, "Install hooks")
-		uninstall  = flag.Bool("uninstall", false, "Uninstall hooks")
-		list       = flag.Bool("list", false, "List available hooks")
-		verbose    = flag.Bool("verbose", false, "Enable verbose output")
-		showVersion = flag.Bool("version", false, "Show version information")
-	)
-	flag.Parse()
+	os.Exit(runCLI(os.Args[1:]))
+}
+
+func runCLI(args []string) int {
+	global := flag.NewFlagSet("hooky", flag.ContinueOnError)
+	global.Usage = printUsage
+
+	var opts globalOptions
+	global.StringVar(&opts.configFile, "config", "hooky.yaml", "Path to configuration file")
+	global.BoolVar(&opts.verbose, "verbose", false, "Enable verbose output")
+	showVersion := global.Bool("version", false, "Show version information")
+
+	// Deprecated aliases for the subcommands, kept for existing scripts and CI
+	legacy := map[string]*bool{
+		"install":   global.Bool("install", false, "Deprecated: use 'hooky install'"),
+		"uninstall": global.Bool("uninstall", false, "Deprecated: use 'hooky uninstall'"),
+		"list":      global.Bool("list", false, "Deprecated: use 'hooky list'"),
+	}
+
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	var legacyCommands []string
+	for _, name := range []string{"install", "uninstall", "list"} {
+		if *legacy[name] {
+			legacyCommands = append(legacyCommands, name)
+		}
+	}
+
+	if len(legacyCommands) > 1 {
+		fmt.Fprintf(os.Stderr, "Error: --%s cannot be combined\n", strings.Join(legacyCommands, ", --"))
+		return exitUsage
+	}
+
+	if global.NArg() == 0 {
+		switch {
+		case len(legacyCommands) == 1:
+			fmt.Fprintf(os.Stderr, "Warning: --%s is deprecated, use 'hooky %s' instead\n", legacyCommands[0], legacyCommands[0])
+			return dispatch(findCommand(legacyCommands[0]), nil, opts)
+		case *showVersion:
+			return dispatch(findCommand("version"), nil, opts)
+		default:
+			printUsage()
+			return exitUsage
+		}
+	}
+
+	if len(legacyCommands) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: --%s cannot be combined with the '%s' command\n", legacyCommands[0], global.Arg(0))
+		return exitUsage
+	}
+
+	cmd := findCommand(global.Arg(0))
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", global.Arg(0))
+		printUsage()
+		return exitUsage
+	}
+
+	return dispatch(cmd, global.Args()[1:], opts)
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// dispatch parses the subcommand's own flags and runs it.
+func dispatch(cmd *command, args []string, opts globalOptions) int {
+	fs := newFlagSet(cmd, &opts)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	return cmd.run(fs, opts)
+}
+
+func newFlagSet(cmd *command, opts *globalOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("hooky "+cmd.name, flag.ContinueOnError)
+	fs.StringVar(&opts.configFile, "config", opts.configFile, "Path to configuration file")
+	fs.BoolVar(&opts.verbose, "verbose", opts.verbose, "Enable verbose output")
+	if cmd.flags != nil {
+		cmd.flags(fs)
+	}
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: hooky %s [options] %s\n\n%s\n\nOptions:\n", cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
 
-	if *showVersion {
-		fmt.Printf("hooky version %s\n", version)
-		return
+	return fs
+}
+
+// flagValue returns the parsed value of a flag defined by a command's flags
+// function.
+func flagValue(fs *flag.FlagSet, name string) interface{} {
+	return fs.Lookup(name).Value.(flag.Getter).Get()
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: hooky [options] <command> [arguments]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  --config string   Path to configuration file (default \"hooky.yaml\")\n")
+	fmt.Fprintf(os.Stderr, "  --verbose         Enable verbose output\n")
+	fmt.Fprintf(os.Stderr, "\nRun 'hooky help <command>' for details on a command.\n")
+}
+
+func runInstall(fs *flag.FlagSet, opts globalOptions) int {
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	manager := NewHookManager(opts.configFile, opts.verbose)
+	if err := manager.InstallHooks(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error installing hooks: %v\n", err)
+		return exitError
 	}
+	fmt.Println("Hooks installed successfully")
+	return exitOK
+}
 
-	manager := NewHookManager(*configFile, *verbose)
+func runUninstall(fs *flag.FlagSet, opts globalOptions) int {
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
 
-	switch {
-	case *install:
-		if err := manager.InstallHooks(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error installing hooks: %v\n", err)
-			os.Exit(1)
+	manager := NewHookManager(opts.configFile, opts.verbose)
+	if err := manager.UninstallHooks(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error uninstalling hooks: %v\n", err)
+		return exitError
+	}
+	fmt.Println("Hooks uninstalled successfully")
+
+	if flagValue(fs, "restore").(bool) {
+		if err := manager.RestoreHooks(0); err != nil {
+			fmt.Fprintf(os.Stderr, "Error restoring hooks: %v\n", err)
+			return exitError
 		}
-		fmt.Println("Hooks installed successfully")
+	}
+	return exitOK
+}
 
-	case *uninstall:
-		if err := manager.UninstallHooks(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error uninstalling hooks: %v\n", err)
-			os.Exit(1)
+func runRestore(fs *flag.FlagSet, opts globalOptions) int {
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	manager := NewHookManager(opts.configFile, opts.verbose)
+	if flagValue(fs, "list").(bool) {
+		if err := manager.ListBackups(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing backups: %v\n", err)
+			return exitError
 		}
-		fmt.Println("Hooks uninstalled successfully")
+		return exitOK
+	}
+
+	if err := manager.RestoreHooks(flagValue(fs, "timestamp").(int64)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error restoring hooks: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func runList(fs *flag.FlagSet, opts globalOptions) int {
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	manager := NewHookManager(opts.configFile, opts.verbose)
+	if err := manager.ListHooks(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing hooks: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
 
-	case *list:
-		if err := manager.ListHooks(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error listing hooks: %v\n", err)
-			os.Exit(1)
+func runMigrate(fs *flag.FlagSet, opts globalOptions) int {
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	manager := NewHookManager(opts.configFile, opts.verbose)
+	if err := manager.MigrateConfig(flagValue(fs, "dry-run").(bool)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error migrating configuration: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func runRun(fs *flag.FlagSet, opts globalOptions) int {
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	hookName := fs.Arg(0)
+	if !isSupportedHook(hookName) {
+		fmt.Fprintf(os.Stderr, "Error: unknown git hook %q\n", hookName)
+		return exitUsage
+	}
+
+	manager := NewHookManager(opts.configFile, opts.verbose)
+	if err := manager.RunHook(hookName, fs.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error running hook %s: %v\n", hookName, err)
+
+		var timeoutErr *TimeoutError
+		switch {
+		case errors.Is(err, errInterrupted):
+			return exitInterrupted
+		case errors.As(err, &timeoutErr):
+			return exitTimeout
+		default:
+			return exitError
 		}
+	}
+	return exitOK
+}
+
+func runStatus(fs *flag.FlagSet, opts globalOptions) int {
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	manager := NewHookManager(opts.configFile, opts.verbose)
+	installed, err := manager.ShowStatus()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error checking status: %v\n", err)
+		return exitError
+	}
+	if !installed {
+		return exitNotInstalled
+	}
+	return exitOK
+}
+
+func runVersion(fs *flag.FlagSet, opts globalOptions) int {
+	fmt.Printf("hooky version %s\n", version)
+	return exitOK
+}
+
+func runHelp(fs *flag.FlagSet, opts globalOptions) int {
+	if fs.NArg() == 0 {
+		printUsage()
+		return exitOK
+	}
 
-	default:
-		flag.Usage()
+	cmd := findCommand(fs.Arg(0))
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n", fs.Arg(0))
+		return exitUsage
 	}
-}
\ No newline at end of file
+	newFlagSet(cmd, &opts).Usage()
+	return exitOK
+}
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"text/template"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 type HookManager struct {
@@ -16,6 +20,10 @@ type HookManager struct {
 	verbose    bool
 	configPath string
 	gitDir     string
+
+	// refreshIncludes fetches configuration included from git repositories
+	// again instead of using the cached copy.
+	refreshIncludes bool
 }
 
 func NewHookManager(configPath string, verbose bool) *HookManager {
@@ -31,7 +39,7 @@ func (hm *HookManager) init() error {
 	}
 
 	// Load configuration
-	config, err := LoadConfig(hm.configPath)
+	config, err := loadConfig(hm.configPath, hm.refreshIncludes)
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
@@ -49,11 +57,20 @@ func (hm *HookManager) init() error {
 	}
 	hm.gitDir = gitDir
 
+	if hm.config.Settings.NestedConfigs {
+		if err := loadNestedConfigs(hm.config, hm.configPath); err != nil {
+			return fmt.Errorf("failed to load nested configuration: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// findGitDirectory returns the git directory shared by all worktrees of the
+// repository, which is where git looks for hooks. In a linked worktree,
+// --git-dir would instead point at .git/worktrees/<name>.
 func (hm *HookManager) findGitDirectory() (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--git-dir")
+	cmd := exec.Command("git", "rev-parse", "--git-common-dir")
 	output, err := cmd.Output()
 	if err != nil {
 		return "", err
@@ -71,26 +88,166 @@ func (hm *HookManager) findGitDirectory() (string, error) {
 	return gitDir, nil
 }
 
+// hooksDir returns the directory hooks are installed into: the hooks_path
+// setting when hooky manages core.hooksPath, otherwise the directory git
+// already runs hooks from, which is core.hooksPath if it is set and the hooks
+// directory inside the git directory if not.
+func (hm *HookManager) hooksDir() (string, error) {
+	if hm.config.Settings.HooksPath != "" {
+		return resolveRepoPath(hm.config.Settings.HooksPath)
+	}
+
+	hooksPath, err := hm.configuredHooksPath()
+	if err != nil {
+		return "", err
+	}
+	if hooksPath != "" {
+		return resolveRepoPath(hooksPath)
+	}
+
+	return filepath.Join(hm.gitDir, "hooks"), nil
+}
+
+// configuredHooksPath returns the core.hooksPath git is configured with, or
+// an empty string if it is not set.
+func (hm *HookManager) configuredHooksPath() (string, error) {
+	output, err := runGit("", "--git-dir="+hm.gitDir, "config", "--path", "--get", "core.hooksPath")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		// git config exits with 1 when the key is not set
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read core.hooksPath: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// checkHooksPath makes sure git will run the hooks installed into hooksDir.
+// With the hooks_path setting, a core.hooksPath pointing elsewhere (set by
+// another tool or by hand) is an error rather than silently replaced.
+func (hm *HookManager) checkHooksPath(hooksDir string) error {
+	hooksPath, err := hm.configuredHooksPath()
+	if err != nil || hooksPath == "" {
+		return err
+	}
+
+	configured, err := resolveRepoPath(hooksPath)
+	if err != nil {
+		return err
+	}
+
+	if hm.config.Settings.HooksPath != "" && filepath.Clean(configured) != filepath.Clean(hooksDir) {
+		return fmt.Errorf("core.hooksPath is already set to %s; unset it with 'git config --unset core.hooksPath' or set hooks_path to %s", hooksPath, hooksPath)
+	}
+
+	if hm.config.Settings.HooksPath == "" {
+		fmt.Printf("Installing into core.hooksPath: %s\n", hooksDir)
+	}
+	return nil
+}
+
+// unsetHooksPath removes core.hooksPath from the repository's configuration
+// if it points to the hooks_path directory.
+func (hm *HookManager) unsetHooksPath() error {
+	output, err := runGit("", "--git-dir="+hm.gitDir, "config", "--local", "--get", "core.hooksPath")
+	if err != nil {
+		// Not set locally, nothing to undo
+		return nil
+	}
+	if filepath.Clean(strings.TrimSpace(output)) != filepath.Clean(hm.config.Settings.HooksPath) {
+		return nil
+	}
+
+	if _, err := runGit("", "--git-dir="+hm.gitDir, "config", "--local", "--unset", "core.hooksPath"); err != nil {
+		return fmt.Errorf("failed to unset core.hooksPath: %w", err)
+	}
+	if hm.config.Settings.Verbose {
+		fmt.Printf("Unset core.hooksPath\n")
+	}
+	return nil
+}
+
+// repoPath returns path relative to the repository root, where steps run,
+// falling back to path itself outside a repository.
+func repoPath(path string) string {
+	resolved, err := resolveRepoPath(path)
+	if err != nil {
+		return path
+	}
+	return resolved
+}
+
+// stepPath returns where a path used by script is found: relative paths are
+// relative to the step's working directory.
+func stepPath(script HookScript, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return repoPath(filepath.Join(script.Dir(), path))
+}
+
+// scriptFile returns the file a script step runs, without its arguments,
+// and whether it exists.
+func scriptFile(script HookScript) (string, bool) {
+	file := script.Script
+	if fields := strings.Fields(script.Script); len(fields) > 0 {
+		file = fields[0]
+	}
+	_, err := os.Stat(stepPath(script, file))
+	return file, !os.IsNotExist(err)
+}
+
+// resolveRepoPath returns path made absolute against the root of the
+// working tree, which is how git interprets a relative core.hooksPath.
+func resolveRepoPath(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	root, err := repoRoot()
+	if err != nil {
+		return "", fmt.Errorf("failed to find repository root: %w", err)
+	}
+	return filepath.Join(root, path), nil
+}
+
 func (hm *HookManager) validateScripts() error {
 	var missingItems []string
+
+	if envFile := hm.config.Settings.EnvFile; envFile != "" {
+		if _, err := readEnvFile(repoPath(envFile)); err != nil {
+			return fmt.Errorf("settings.env_file: %w", err)
+		}
+	}
 	
 	for hookName, scripts := range hm.config.Hooks {
 		for _, script := range scripts {
+			if script.WorkingDir != "" {
+				if info, err := os.Stat(repoPath(script.Dir())); err != nil || !info.IsDir() {
+					missingItems = append(missingItems, fmt.Sprintf("working directory '%s' not found (from: %s, hook: %s)", script.WorkingDir, script.Name, hookName))
+					continue
+				}
+			}
+
+			// Commands are looked up in the PATH the step runs with
+			vars, err := hm.stepVars(script)
+			if err != nil {
+				missingItems = append(missingItems, fmt.Sprintf("%v (hook: %s)", err, hookName))
+				continue
+			}
+
 			if script.Script != "" {
 				// Validate script file exists
-				// If it has arguments, only check the first part (the actual file)
-				scriptPath := script.Script
-				if strings.Contains(scriptPath, " ") {
-					scriptPath = strings.Fields(scriptPath)[0]
-				}
-				
-				if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
-					missingItems = append(missingItems, fmt.Sprintf("script file '%s' not found (from: %s, hook: %s)", scriptPath, script.Script, hookName))
+				if scriptPath, ok := scriptFile(script); !ok {
+					missing := fmt.Sprintf("script file '%s' not found (from: %s, hook: %s)", scriptPath, script.Script, hookName)
+					if _, isCommand := commandProgram(script, script.Script, vars); isCommand {
+						missing += "; it looks like a command, use 'command' instead or run 'hooky migrate'"
+					}
+					missingItems = append(missingItems, missing)
 				}
 			} else if script.Command != "" {
 				// Validate command exists in PATH
-				cmd := strings.Fields(script.Command)[0]
-				if _, err := exec.LookPath(cmd); err != nil {
+				if cmd, ok := commandProgram(script, script.Command, vars); !ok {
 					missingItems = append(missingItems, fmt.Sprintf("command '%s' not found in PATH (from: %s, hook: %s)", cmd, script.Command, hookName))
 				}
 			}
@@ -105,6 +262,8 @@ func (hm *HookManager) validateScripts() error {
 }
 
 func (hm *HookManager) InstallHooks() error {
+	// Pick up changes to shared configuration on every install
+	hm.refreshIncludes = true
 	if err := hm.init(); err != nil {
 		return err
 	}
@@ -114,8 +273,15 @@ func (hm *HookManager) InstallHooks() error {
 		return err
 	}
 
-	hooksDir := filepath.Join(hm.gitDir, "hooks")
-	
+	hooksDir, err := hm.hooksDir()
+	if err != nil {
+		return err
+	}
+
+	if err := hm.checkHooksPath(hooksDir); err != nil {
+		return err
+	}
+
 	// Create hooks directory if it doesn't exist
 	if err := os.MkdirAll(hooksDir, 0755); err != nil {
 		return fmt.Errorf("failed to create hooks directory: %w", err)
@@ -143,15 +309,41 @@ func (hm *HookManager) InstallHooks() error {
 		}
 	}
 
+	if hm.config.Settings.HooksPath != "" {
+		if _, err := runGit("", "--git-dir="+hm.gitDir, "config", "core.hooksPath", filepath.ToSlash(hm.config.Settings.HooksPath)); err != nil {
+			return fmt.Errorf("failed to set core.hooksPath: %w", err)
+		}
+		if hm.config.Settings.Verbose {
+			fmt.Printf("Set core.hooksPath to %s\n", hm.config.Settings.HooksPath)
+		}
+	}
+
+	if hm.config.Settings.Lockfile != "off" {
+		if err := hm.writeLock(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (hm *HookManager) installHook(hookName string, scripts []HookScript, hooksDir string) error {
 	hookPath := filepath.Join(hooksDir, hookName)
 	
-	// Backup existing hook if it exists and backup is enabled
-	if hm.config.Settings.BackupExisting {
-		if _, err := os.Stat(hookPath); err == nil {
+	if _, err := os.Stat(hookPath); err == nil {
+		generated, err := isHookyHook(hookPath)
+		if err != nil {
+			return fmt.Errorf("failed to read existing hook: %w", err)
+		}
+
+		switch {
+		case hm.config.Settings.PreserveExisting == "chain" && !generated:
+			if err := hm.chainHook(hookName, hookPath); err != nil {
+				return err
+			}
+		case hm.config.Settings.BackupExisting && !generated:
+			// Backup existing hook if it exists and backup is enabled; hooky's
+			// own hook is simply replaced
 			backupDir := filepath.Join(hm.gitDir, hm.config.Settings.BackupDirectory)
 			backupPath := filepath.Join(backupDir, fmt.Sprintf("%s.%d", hookName, time.Now().Unix()))
 			
@@ -166,7 +358,7 @@ func (hm *HookManager) installHook(hookName string, scripts []HookScript, hooksD
 	}
 
 	// Generate hook script content
-	content, err := hm.generateHookScript(hookName, scripts)
+	content, err := hm.generateHookScript(hookName)
 	if err != nil {
 		return fmt.Errorf("failed to generate hook script: %w", err)
 	}
@@ -183,41 +375,51 @@ func (hm *HookManager) installHook(hookName string, scripts []HookScript, hooksD
 	return nil
 }
 
-func (hm *HookManager) generateHookScript(hookName string, scripts []HookScript) (string, error) {
+func (hm *HookManager) generateHookScript(hookName string) (string, error) {
+	configPath, err := hm.hookConfigPath()
+	if err != nil {
+		return "", err
+	}
+
 	tmpl := `#!/bin/sh
 # Generated by hooky - Do not edit manually
 # Hook: {{.HookName}}
+{{- if .Timestamp}}
 # Generated at: {{.Timestamp}}
+{{- end}}
+#
+# Steps are read from {{.ConfigPath}} each time the hook runs.
 
-set -e
+# Run from the repository root, wherever the repository has been moved to
+root=$(git rev-parse --show-toplevel 2>/dev/null) && cd "$root"
 
-{{range .Scripts}}
-# {{.Description}}
-echo "Running: {{.Name}}"
-cd "{{$.WorkingDir}}"
-{{if .Script}}{{.Script}} "$@"{{else}}{{.Command}} "$@"{{end}}
-if [ $? -ne 0 ]; then
-    echo "Hook failed: {{.Name}}"
-    exit 1
+{{if .Executable -}}
+hooky="{{.Executable}}"
+if [ ! -x "$hooky" ]; then
+    hooky=hooky
 fi
-{{end}}
-`
+{{- else -}}
+# The hook is shared with everyone who checks out the repository
+hooky=hooky
+{{- end}}
 
-	workingDir, err := os.Getwd()
-	if err != nil {
-		return "", err
-	}
+exec "$hooky" --config "{{.ConfigPath}}" run {{.HookName}} "$@"
+`
 
 	data := struct {
 		HookName   string
 		Timestamp  string
-		Scripts    []HookScript
-		WorkingDir string
+		ConfigPath string
+		Executable string
 	}{
 		HookName:   hookName,
-		Timestamp:  time.Now().Format(time.RFC3339),
-		Scripts:    scripts,
-		WorkingDir: workingDir,
+		ConfigPath: filepath.ToSlash(configPath),
+	}
+	// Hooks in a tracked hooks_path directory are committed, so they must
+	// not change on every install or point at one machine's binary
+	if hm.config.Settings.HooksPath == "" {
+		data.Timestamp = time.Now().Format(time.RFC3339)
+		data.Executable = hookyExecutable()
 	}
 
 	t, err := template.New("hook").Parse(tmpl)
@@ -239,12 +441,56 @@ fi
 	return script, nil
 }
 
+// hookConfigPath returns the configuration path to bake into hooks. Hooks
+// run from the repository root, so a configuration inside the repository is
+// referenced relative to the root; this keeps hooks working when installed
+// from a subdirectory and after the repository is moved or cloned.
+func (hm *HookManager) hookConfigPath() (string, error) {
+	configPath, err := filepath.Abs(hm.configPath)
+	if err != nil {
+		return "", err
+	}
+
+	root, err := repoRoot()
+	if err != nil {
+		// Not in a work tree, e.g. a bare repository: keep the absolute path
+		return configPath, nil
+	}
+
+	// git reports the root with symlinks resolved, so compare like with like
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(configPath)); err == nil {
+		configPath = filepath.Join(dir, filepath.Base(configPath))
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	rel, err := filepath.Rel(root, configPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return configPath, nil
+	}
+	return rel, nil
+}
+
+// hookyExecutable returns the path of the running binary so generated hooks
+// call the same hooky that installed them. Hooks fall back to PATH if it moves.
+func hookyExecutable() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return "hooky"
+	}
+	return filepath.ToSlash(exe)
+}
+
 func (hm *HookManager) UninstallHooks() error {
 	if err := hm.init(); err != nil {
 		return err
 	}
 
-	hooksDir := filepath.Join(hm.gitDir, "hooks")
+	hooksDir, err := hm.hooksDir()
+	if err != nil {
+		return err
+	}
 
 	for hookName := range hm.config.Hooks {
 		hookPath := filepath.Join(hooksDir, hookName)
@@ -257,12 +503,12 @@ func (hm *HookManager) UninstallHooks() error {
 		}
 
 		// Check if this is a hooky-generated hook
-		content, err := os.ReadFile(hookPath)
+		generated, err := isHookyHook(hookPath)
 		if err != nil {
 			return fmt.Errorf("failed to read hook %s: %w", hookName, err)
 		}
 
-		if !strings.Contains(string(content), "Generated by hooky") {
+		if !generated {
 			if hm.config.Settings.Verbose {
 				fmt.Printf("Skipping non-hooky hook: %s\n", hookName)
 			}
@@ -276,17 +522,143 @@ func (hm *HookManager) UninstallHooks() error {
 		if hm.config.Settings.Verbose {
 			fmt.Printf("Removed hook: %s\n", hookName)
 		}
+
+		// Put back the hook that hooky was chaining to
+		if err := hm.unchainHook(hookName, hookPath); err != nil {
+			return err
+		}
+	}
+
+	if hm.config.Settings.HooksPath != "" {
+		if err := hm.unsetHooksPath(); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// isHookyHook reports whether the hook file at path was generated by hooky.
+func isHookyHook(path string) (bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(content), "Generated by hooky"), nil
+}
+
+// ShowStatus prints the installation state of every configured hook and of
+// any hooky-generated hook that is no longer configured. It reports whether
+// all configured hooks are installed.
+func (hm *HookManager) ShowStatus() (bool, error) {
+	if err := hm.init(); err != nil {
+		return false, err
+	}
+
+	hooksDir, err := hm.hooksDir()
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Printf("Configuration: %s\n", hm.configPath)
+	fmt.Printf("Hooks directory: %s\n\n", hooksDir)
+	printWorktrees()
+
+	allInstalled := true
+	for _, hookName := range GetSupportedHooks() {
+		configured := len(hm.config.Hooks[hookName]) > 0
+		hookPath := filepath.Join(hooksDir, hookName)
+
+		var state string
+		installed := false
+		if _, err := os.Stat(hookPath); os.IsNotExist(err) {
+			if !configured {
+				continue
+			}
+			state = "❌ not installed"
+		} else {
+			generated, err := isHookyHook(hookPath)
+			if err != nil {
+				return false, fmt.Errorf("failed to read hook %s: %w", hookName, err)
+			}
+			switch {
+			case generated && configured:
+				state = "✅ installed"
+				installed = true
+				if _, err := os.Stat(hm.chainedHookPath(hookName)); err == nil {
+					state += " (chains existing hook)"
+				}
+			case generated:
+				state = "⚠️  installed but not configured"
+			case configured:
+				state = "❌ not installed (existing non-hooky hook)"
+			default:
+				continue
+			}
+		}
+
+		if configured && !installed {
+			allInstalled = false
+		}
+		fmt.Printf("  %-20s %s\n", hookName, state)
+	}
+
+	return allInstalled, nil
+}
+
+// printWorktrees lists the worktrees that run the installed hooks, if the
+// repository has more than one. Hooks are installed once per repository, so
+// every worktree runs them.
+func printWorktrees() {
+	worktrees, err := listWorktrees()
+	if err != nil || len(worktrees) < 2 {
+		return
+	}
+
+	fmt.Printf("Worktrees sharing these hooks:\n")
+	for _, worktree := range worktrees {
+		fmt.Printf("  %s\n", worktree)
+	}
+	fmt.Println()
+}
+
+// printExpandedSettings shows the settings whose values came from
+// variables, as written and as expanded.
+func (hm *HookManager) printExpandedSettings() {
+	if len(hm.config.RawSettings) == 0 {
+		return
+	}
+
+	var values map[string]interface{}
+	if data, err := yaml.Marshal(hm.config.Settings); err == nil {
+		yaml.Unmarshal(data, &values)
+	}
+
+	keys := make([]string, 0, len(hm.config.RawSettings))
+	for key := range hm.config.RawSettings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Println("Settings from variables:")
+	for _, key := range keys {
+		fmt.Printf("  %s: %v (raw: %s)\n", key, values[key], hm.config.RawSettings[key])
+	}
+	fmt.Println()
+}
+
 func (hm *HookManager) ListHooks() error {
 	if err := hm.init(); err != nil {
 		return err
 	}
 
-	fmt.Printf("Configuration: %s\n\n", hm.configPath)
+	configFiles := hm.config.Files
+	if len(configFiles) == 0 {
+		configFiles = []string{hm.configPath}
+	}
+	fmt.Printf("Configuration: %s\n\n", strings.Join(configFiles, ", "))
+	printWorktrees()
+	hm.printExpandedSettings()
 
 	if len(hm.config.Hooks) == 0 {
 		fmt.Println("No hooks configured")
@@ -297,6 +669,7 @@ func (hm *HookManager) ListHooks() error {
 
 	for hookName, scripts := range hm.config.Hooks {
 		fmt.Printf("Hook: %s\n", hookName)
+		var conditions *conditionContext
 		
 		if len(scripts) == 0 {
 			fmt.Printf("  No scripts configured\n")
@@ -304,27 +677,30 @@ func (hm *HookManager) ListHooks() error {
 			for i, script := range scripts {
 				status := "✅"
 				var scriptType, scriptValue string
+
+				vars, err := hm.stepVars(script)
+				if err != nil {
+					status = "❌ MISSING"
+					missingScripts = append(missingScripts, fmt.Sprintf("%v (hook: %s)", err, hookName))
+				}
 				
 				if script.Script != "" {
 					scriptType = "script"
 					scriptValue = script.Script
 					// For script files, check if file exists
-					// If it has arguments, only check the first part (the actual file)
-					scriptPath := script.Script
-					if strings.Contains(scriptPath, " ") {
-						scriptPath = strings.Fields(scriptPath)[0]
-					}
-					
-					if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
+					if scriptPath, ok := scriptFile(script); !ok {
 						status = "❌ MISSING"
-						missingScripts = append(missingScripts, fmt.Sprintf("script file '%s' not found (from: %s, hook: %s)", scriptPath, script.Script, hookName))
+						missing := fmt.Sprintf("script file '%s' not found (from: %s, hook: %s)", scriptPath, script.Script, hookName)
+						if _, isCommand := commandProgram(script, script.Script, vars); isCommand {
+							missing += "; it looks like a command, use 'command' instead or run 'hooky migrate'"
+						}
+						missingScripts = append(missingScripts, missing)
 					}
 				} else if script.Command != "" {
 					scriptType = "command"
 					scriptValue = script.Command
 					// For commands, check if command exists in PATH
-					cmd := strings.Fields(script.Command)[0]
-					if _, err := exec.LookPath(cmd); err != nil {
+					if cmd, ok := commandProgram(script, script.Command, vars); !ok {
 						status = "❌ MISSING"
 						missingScripts = append(missingScripts, fmt.Sprintf("command '%s' not found in PATH (from: %s, hook: %s)", cmd, script.Command, hookName))
 					}
@@ -334,7 +710,58 @@ func (hm *HookManager) ListHooks() error {
 				if script.Description != "" {
 					fmt.Printf("     %s\n", script.Description)
 				}
+				if script.Raw != "" {
+					fmt.Printf("     raw: %s\n", script.Raw)
+				}
+				if len(hm.config.Files) > 1 {
+					fmt.Printf("     from: %s\n", script.Source)
+				}
+				if len(script.Overridden) > 0 {
+					fmt.Printf("     overridden locally: %s\n", strings.Join(script.Overridden, ", "))
+				}
+				if script.WorkingDir != "" {
+					fmt.Printf("     working_dir: %s\n", script.WorkingDir)
+				}
+				if len(script.Env) > 0 {
+					fmt.Printf("     env: %s\n", strings.Join(appendVars(nil, script.Env), ", "))
+				}
+				if script.EnvFile != "" {
+					fmt.Printf("     env_file: %s\n", script.EnvFile)
+				}
+				if len(script.Files) > 0 {
+					fmt.Printf("     files: %s\n", strings.Join(script.Files, ", "))
+				}
+				if len(script.Exclude) > 0 {
+					fmt.Printf("     exclude: %s\n", strings.Join(script.Exclude, ", "))
+				}
+				if len(script.OnlyRefs) > 0 {
+					fmt.Printf("     only_refs: %s\n", strings.Join(script.OnlyRefs, ", "))
+				}
+				if script.If != "" {
+					if conditions == nil {
+						conditions = listConditionContext(hookName)
+					}
+					if hookName == "pre-push" && dependsOnPush(script.If) {
+						fmt.Printf("     if: %s (depends on the push)\n", script.If)
+					} else {
+						run, reason := evalCondition(script, conditions.withVars(vars))
+						verdict := "would be skipped"
+						if run {
+							verdict = "would run"
+						}
+						fmt.Printf("     if: %s (%s: %s)\n", script.If, verdict, reason)
+					}
+				}
+			}
+		}
+		for _, script := range hm.config.Disabled[hookName] {
+			where := "in " + script.Source
+			for _, field := range script.Overridden {
+				if field == "disabled" {
+					where = "locally"
+				}
 			}
+			fmt.Printf("  - %s [disabled %s]\n", script.Name, where)
 		}
 		fmt.Println()
 	}
@@ -148,7 +148,7 @@ func TestGenerateHookScript(t *testing.T) {
 				{Name: "test", Script: "test.sh", Description: "Test script"},
 			},
 			expectError: false,
-			contains:    []string{"#!/bin/sh", "test.sh", "Running: test", "Test script"},
+			contains:    []string{"#!/bin/sh", "# Hook: pre-commit", "run pre-commit \"$@\""},
 		},
 		{
 			name:     "single command",
@@ -157,7 +157,7 @@ func TestGenerateHookScript(t *testing.T) {
 				{Name: "test", Command: "go test", Description: "Test command"},
 			},
 			expectError: false,
-			contains:    []string{"#!/bin/sh", "go test", "Running: test", "Test command"},
+			contains:    []string{"#!/bin/sh", "--config \"test-config.yaml\"", "run pre-commit"},
 		},
 		{
 			name:     "mixed script and command",
@@ -167,7 +167,7 @@ func TestGenerateHookScript(t *testing.T) {
 				{Name: "command-test", Command: "go test", Description: "Command test"},
 			},
 			expectError: false,
-			contains:    []string{"test.sh", "go test", "Running: script-test", "Running: command-test"},
+			contains:    []string{"exec \"$hooky\"", "run pre-commit"},
 		},
 		{
 			name:     "multiple scripts",
@@ -177,17 +177,16 @@ func TestGenerateHookScript(t *testing.T) {
 				{Name: "test", Command: "npm test", Description: "Test"},
 			},
 			expectError: false,
-			contains:    []string{"build.sh", "npm test", "Running: build", "Running: test"},
+			contains:    []string{"# Hook: pre-push", "run pre-push \"$@\""},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Create temporary git directory
+			// Create temporary git repository
 			gitDir := filepath.Join(tmpDir, ".git")
-			err := os.MkdirAll(gitDir, 0755)
-			if err != nil {
-				t.Fatalf("Failed to create git dir: %v", err)
+			if err := exec.Command("git", "init", "-q", tmpDir).Run(); err != nil {
+				t.Fatalf("Failed to init git repo: %v", err)
 			}
 
 			hm := &HookManager{
@@ -195,7 +194,8 @@ func TestGenerateHookScript(t *testing.T) {
 					Hooks:    map[string][]HookScript{tt.hookName: tt.scripts},
 					Settings: Settings{},
 				},
-				gitDir: gitDir,
+				configPath: "test-config.yaml",
+				gitDir:     gitDir,
 			}
 
 			// Change to temp directory for working directory
@@ -203,7 +203,7 @@ func TestGenerateHookScript(t *testing.T) {
 			defer os.Chdir(oldDir)
 			os.Chdir(tmpDir)
 
-			content, err := hm.generateHookScript(tt.hookName, tt.scripts)
+			content, err := hm.generateHookScript(tt.hookName)
 
 			if tt.expectError {
 				if err == nil {
@@ -232,6 +232,11 @@ func TestGenerateHookScript(t *testing.T) {
 			if !strings.Contains(content, "Generated by hooky") {
 				t.Error("Expected script to contain hooky signature")
 			}
+
+			// Git runs hooks from the root of the worktree they fire in
+			if strings.Contains(content, "\ncd ") {
+				t.Error("Hook should not change to the directory it was installed from")
+			}
 		})
 	}
 }
@@ -365,11 +370,8 @@ func TestInstallHooks(t *testing.T) {
 	}
 
 	hookContent := string(content)
-	if !strings.Contains(hookContent, "test.sh") {
-		t.Error("Hook content should contain script path")
-	}
-	if !strings.Contains(hookContent, "echo test") {
-		t.Error("Hook content should contain command")
+	if !strings.Contains(hookContent, "run pre-commit") {
+		t.Error("Hook content should delegate to hooky run")
 	}
 	if !strings.Contains(hookContent, "Generated by hooky") {
 		t.Error("Hook content should contain hooky signature")
@@ -440,4 +442,222 @@ func runCommand(t *testing.T, name string, args ...string) (string, error) {
 	cmd := exec.Command(name, args...)
 	output, err := cmd.CombinedOutput()
 	return string(output), err
-}
\ No newline at end of file
+}
+func TestShowStatus(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	gitDir := filepath.Join(tmpDir, ".git")
+	hooksDir := filepath.Join(gitDir, "hooks")
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		t.Fatalf("Failed to create git hooks dir: %v", err)
+	}
+
+	hooks := map[string][]HookScript{
+		"pre-commit": {{Name: "test", Command: "echo test", Description: "Test"}},
+		"pre-push":   {{Name: "test", Command: "echo test", Description: "Test"}},
+	}
+
+	hm := &HookManager{
+		config: &Config{Hooks: hooks, Settings: Settings{}},
+		gitDir: gitDir,
+	}
+
+	installed, err := hm.ShowStatus()
+	if err != nil {
+		t.Fatalf("ShowStatus failed: %v", err)
+	}
+	if installed {
+		t.Error("Expected hooks to be reported as not installed")
+	}
+
+	for hookName := range hooks {
+		content := "#!/bin/sh\n# Generated by hooky - Do not edit manually\n"
+		if err := os.WriteFile(filepath.Join(hooksDir, hookName), []byte(content), 0755); err != nil {
+			t.Fatalf("Failed to write hook: %v", err)
+		}
+	}
+
+	installed, err = hm.ShowStatus()
+	if err != nil {
+		t.Fatalf("ShowStatus failed: %v", err)
+	}
+	if !installed {
+		t.Error("Expected hooks to be reported as installed")
+	}
+
+	// A hand-written hook in place of a configured one is not an install
+	if err := os.WriteFile(filepath.Join(hooksDir, "pre-push"), []byte("#!/bin/sh\necho custom\n"), 0755); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+
+	installed, err = hm.ShowStatus()
+	if err != nil {
+		t.Fatalf("ShowStatus failed: %v", err)
+	}
+	if installed {
+		t.Error("Expected a non-hooky hook to be reported as not installed")
+	}
+}
+
+func TestInstallHooksPath(t *testing.T) {
+	tests := []struct {
+		name              string
+		hooksPathSetting  string
+		configuredPath    string
+		expectedHook      string
+		expectedHooksPath string
+		expectError       bool
+	}{
+		{
+			name:         "default hooks directory",
+			expectedHook: ".git/hooks/pre-commit",
+		},
+		{
+			name:              "tracked hooks directory",
+			hooksPathSetting:  ".hooky/hooks",
+			expectedHook:      ".hooky/hooks/pre-commit",
+			expectedHooksPath: ".hooky/hooks",
+		},
+		{
+			name:              "tracked hooks directory already configured",
+			hooksPathSetting:  ".hooky/hooks",
+			configuredPath:    ".hooky/hooks",
+			expectedHook:      ".hooky/hooks/pre-commit",
+			expectedHooksPath: ".hooky/hooks",
+		},
+		{
+			name:              "existing core.hooksPath is respected",
+			configuredPath:    "custom-hooks",
+			expectedHook:      "custom-hooks/pre-commit",
+			expectedHooksPath: "custom-hooks",
+		},
+		{
+			name:             "conflicting core.hooksPath",
+			hooksPathSetting: ".hooky/hooks",
+			configuredPath:   "custom-hooks",
+			expectError:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "echo"
+      command: "echo test"
+      description: "Echo"
+settings:
+  hooks_path: "`+tt.hooksPathSetting+`"
+`)
+			if tt.configuredPath != "" {
+				gitRun(t, tmpDir, "config", "core.hooksPath", tt.configuredPath)
+			}
+
+			err := NewHookManager("hooky.yaml", false).InstallHooks()
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("InstallHooks failed: %v", err)
+			}
+
+			content := readFile(t, tmpDir, tt.expectedHook)
+			if !strings.Contains(content, "Generated by hooky") {
+				t.Errorf("Expected a hooky hook at %s", tt.expectedHook)
+			}
+			if tt.hooksPathSetting != "" {
+				// Tracked hooks are the same for every install and machine
+				if strings.Contains(content, "Generated at") || !strings.Contains(content, "\nhooky=hooky\n") {
+					t.Errorf("Expected a hook without timestamp or binary path, got:\n%s", content)
+				}
+				if err := NewHookManager("hooky.yaml", false).InstallHooks(); err != nil {
+					t.Fatalf("InstallHooks failed: %v", err)
+				}
+				if reinstalled := readFile(t, tmpDir, tt.expectedHook); reinstalled != content {
+					t.Errorf("Expected reinstalling to leave the hook unchanged, got:\n%s", reinstalled)
+				}
+			}
+			if tt.expectedHook != ".git/hooks/pre-commit" {
+				if _, err := os.Stat(filepath.Join(tmpDir, ".git/hooks/pre-commit")); !os.IsNotExist(err) {
+					t.Error("Hooks should not be written to .git/hooks when core.hooksPath is used")
+				}
+			}
+
+			hooksPath, _ := runGit(tmpDir, "config", "--get", "core.hooksPath")
+			if strings.TrimSpace(hooksPath) != tt.expectedHooksPath {
+				t.Errorf("Expected core.hooksPath %q, got %q", tt.expectedHooksPath, hooksPath)
+			}
+
+			if err := NewHookManager("hooky.yaml", false).UninstallHooks(); err != nil {
+				t.Fatalf("UninstallHooks failed: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(tmpDir, tt.expectedHook)); !os.IsNotExist(err) {
+				t.Error("Expected the hook to be removed")
+			}
+
+			// Only the core.hooksPath that hooky manages is unset
+			expectedAfter := tt.configuredPath
+			if tt.hooksPathSetting != "" {
+				expectedAfter = ""
+			}
+			hooksPath, _ = runGit(tmpDir, "config", "--get", "core.hooksPath")
+			if strings.TrimSpace(hooksPath) != expectedAfter {
+				t.Errorf("Expected core.hooksPath %q after uninstall, got %q", expectedAfter, hooksPath)
+			}
+		})
+	}
+}
+
+func TestWorktrees(t *testing.T) {
+	mainDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "stash-is-per-worktree"
+      command: "test -d \"$(git rev-parse --absolute-git-dir)/hooky-stash\""
+      description: "Checks where unstaged changes are stashed"
+settings:
+  stash_unstaged: true
+`)
+	writeFile(t, mainDir, "f", "committed\n")
+	gitRun(t, mainDir, "add", ".")
+	gitRun(t, mainDir, "commit", "-q", "-m", "initial")
+
+	worktreeDir := filepath.Join(t.TempDir(), "wt")
+	gitRun(t, mainDir, "worktree", "add", "-q", worktreeDir)
+	os.Chdir(worktreeDir)
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.InstallHooks(); err != nil {
+		t.Fatalf("InstallHooks failed: %v", err)
+	}
+
+	expectedGitDir, _ := filepath.EvalSymlinks(filepath.Join(mainDir, ".git"))
+	if gitDir, _ := filepath.EvalSymlinks(hm.gitDir); gitDir != expectedGitDir {
+		t.Errorf("Expected the common git directory %s, got %s", expectedGitDir, gitDir)
+	}
+	if content := readFile(t, mainDir, ".git/hooks/pre-commit"); !strings.Contains(content, "run pre-commit") {
+		t.Error("Expected the hook to be installed in the common hooks directory")
+	}
+
+	worktrees, err := listWorktrees()
+	if err != nil {
+		t.Fatalf("listWorktrees failed: %v", err)
+	}
+	if len(worktrees) != 2 || !strings.HasSuffix(worktrees[1], "wt") {
+		t.Errorf("Expected the main and linked worktrees, got %v", worktrees)
+	}
+
+	// The hook runs against the worktree it is invoked in
+	writeFile(t, worktreeDir, "f", "committed\nunstaged\n")
+	gitRun(t, worktreeDir, "add", "hooky.yaml")
+	if err := NewHookManager("hooky.yaml", false).RunHook("pre-commit", nil); err != nil {
+		t.Errorf("RunHook failed: %v", err)
+	}
+	if content := readFile(t, worktreeDir, "f"); content != "committed\nunstaged\n" {
+		t.Errorf("Expected unstaged changes to be restored in the worktree, got %q", content)
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configVersion is the current version of the configuration format. Earlier
+// versions are:
+//   - 1: hooky 1.0, script names are relative to a top-level hooks_directory
+//   - 2: hooky 1.1 and 1.2, script runs either a file or a command
+//
+// Files without a version key are read as the current version.
+const configVersion = 3
+
+// checkConfigVersion rejects configuration files in a format this version
+// of hooky cannot read.
+func checkConfigVersion(version int, hooksDirectory string) error {
+	switch {
+	case version < 0:
+		return fmt.Errorf("invalid version %d", version)
+	case version > configVersion:
+		return fmt.Errorf("version %d is newer than this hooky supports (%d), upgrade hooky", version, configVersion)
+	case hooksDirectory != "":
+		return fmt.Errorf("hooks_directory was removed in hooky 1.1.0, run 'hooky migrate' to upgrade the configuration")
+	case version != 0 && version < configVersion:
+		return fmt.Errorf("version %d is outdated, run 'hooky migrate' to upgrade the configuration to version %d", version, configVersion)
+	}
+	return nil
+}
+
+// migration describes what migrateConfig changed in a configuration, and
+// what it could not decide.
+type migration struct {
+	From     int
+	Changes  []string
+	Warnings []string
+}
+
+// migrateConfig rewrites the configuration in data to the current version.
+// It edits the parsed YAML nodes, so comments and the order of keys and
+// steps survive; blank lines and indentation are normalized.
+//
+// Legacy script entries that do not name an existing file but a program in
+// PATH become commands, using the same checks as validateScripts.
+func migrateConfig(data []byte) ([]byte, *migration, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("config file must contain a mapping")
+	}
+	root := doc.Content[0]
+
+	m := &migration{}
+	versionNode := mappingValue(root, "version")
+	if versionNode != nil {
+		if err := versionNode.Decode(&m.From); err != nil {
+			return nil, nil, fmt.Errorf("line %d: invalid version: %w", versionNode.Line, err)
+		}
+		if m.From > configVersion {
+			return nil, nil, fmt.Errorf("version %d is newer than this hooky supports (%d)", m.From, configVersion)
+		}
+	}
+
+	hooksDirectory := ""
+	if node := mappingValue(root, "hooks_directory"); node != nil {
+		hooksDirectory = node.Value
+		// A comment above the first key is the file's header
+		if first := root.Content[0]; first.Value == "hooks_directory" {
+			doc.HeadComment = joinComments(doc.HeadComment, first.HeadComment)
+			first.HeadComment = ""
+		}
+		removeMappingKey(root, "hooks_directory")
+		m.Changes = append(m.Changes, fmt.Sprintf("removed hooks_directory %q and prefixed it to script paths", hooksDirectory))
+	}
+
+	switch {
+	case m.From == configVersion && hooksDirectory == "":
+		return data, m, nil
+	case m.From == 0 && hooksDirectory != "":
+		m.From = 1
+	case m.From == 0:
+		m.From = 2
+	}
+
+	if hooks := mappingValue(root, "hooks"); hooks != nil && hooks.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(hooks.Content); i += 2 {
+			hookName := hooks.Content[i].Value
+			steps := hooks.Content[i+1]
+			if steps.Kind != yaml.SequenceNode {
+				continue
+			}
+			for j, step := range steps.Content {
+				migrateStep(m, fmt.Sprintf("hooks.%s[%d]", hookName, j), step, hooksDirectory)
+			}
+		}
+	}
+
+	if versionNode == nil {
+		// A comment at the very top of the file describes the file, not its
+		// first key, so keep it above the new version key
+		if doc.HeadComment == "" && len(root.Content) > 0 {
+			doc.HeadComment = root.Content[0].HeadComment
+			root.Content[0].HeadComment = ""
+		}
+		root.Content = append([]*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "version", HeadComment: "# Configuration format version, upgraded by 'hooky migrate'"},
+			{Kind: yaml.ScalarNode, Tag: "!!int"},
+		}, root.Content...)
+		versionNode = root.Content[1]
+	}
+	versionNode.Value = strconv.Itoa(configVersion)
+	versionNode.Style = 0
+	m.Changes = append(m.Changes, fmt.Sprintf("set version: %d", configVersion))
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to write config file: %w", err)
+	}
+	return buf.Bytes(), m, nil
+}
+
+// migrateStep updates a legacy script entry of a step. With hooksDirectory,
+// from version 1, scripts were always files in that directory.
+func migrateStep(m *migration, label string, step *yaml.Node, hooksDirectory string) {
+	if step.Kind != yaml.MappingNode || mappingValue(step, "command") != nil {
+		return
+	}
+	scriptNode := mappingValue(step, "script")
+	if scriptNode == nil || scriptNode.Kind != yaml.ScalarNode {
+		return
+	}
+
+	var script HookScript
+	_ = step.Decode(&script)
+	if script.Name != "" {
+		label += fmt.Sprintf(" (%s)", script.Name)
+	}
+
+	if hooksDirectory != "" {
+		file, args, _ := strings.Cut(strings.TrimSpace(scriptNode.Value), " ")
+		updated := path.Join(hooksDirectory, file)
+		if args != "" {
+			updated += " " + args
+		}
+		m.Changes = append(m.Changes, fmt.Sprintf("%s: script %q -> %q", label, scriptNode.Value, updated))
+		scriptNode.Value = updated
+		return
+	}
+
+	if _, ok := scriptFile(script); ok {
+		return
+	}
+	if _, ok := commandProgram(script, script.Script, nil); ok {
+		for i := 0; i+1 < len(step.Content); i += 2 {
+			if step.Content[i+1] == scriptNode {
+				step.Content[i].Value = "command"
+			}
+		}
+		m.Changes = append(m.Changes, fmt.Sprintf("%s: script -> command %q", label, script.Script))
+		return
+	}
+	m.Warnings = append(m.Warnings, fmt.Sprintf("%s: %q is neither a file nor a command in PATH, left as script", label, script.Script))
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// removeMappingKey removes key and its value from a mapping node. The
+// comment above the key moves to the next key, or below the mapping when
+// the key was the last one.
+func removeMappingKey(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		if comment := mapping.Content[i].HeadComment; comment != "" {
+			if i+2 < len(mapping.Content) {
+				next := mapping.Content[i+2]
+				next.HeadComment = joinComments(comment, next.HeadComment)
+			} else {
+				mapping.FootComment = joinComments(mapping.FootComment, comment)
+			}
+		}
+		mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+		return
+	}
+}
+
+// joinComments joins YAML comments, skipping empty ones.
+func joinComments(comments ...string) string {
+	var joined []string
+	for _, comment := range comments {
+		if comment != "" {
+			joined = append(joined, comment)
+		}
+	}
+	return strings.Join(joined, "\n")
+}
+
+// MigrateConfig upgrades the configuration file to the current version in
+// place, or with dryRun prints the result instead. Included files are not
+// followed; migrate them with --config.
+func (hm *HookManager) MigrateConfig(dryRun bool) error {
+	data, err := os.ReadFile(hm.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	migrated, m, err := migrateConfig(data)
+	if err != nil {
+		return err
+	}
+	if len(m.Changes) == 0 {
+		fmt.Printf("%s is already at version %d\n", hm.configPath, configVersion)
+		return nil
+	}
+
+	// Check the result before replacing a working file with it
+	var file configFile
+	if err := yaml.Unmarshal(migrated, &file); err != nil {
+		return fmt.Errorf("migrated configuration is invalid: %w", err)
+	}
+	if err := validateHookScripts(file.Hooks); err != nil {
+		return fmt.Errorf("migrated configuration is invalid: %w", err)
+	}
+
+	fmt.Printf("Migrating %s from version %d to %d:\n", hm.configPath, m.From, configVersion)
+	for _, change := range m.Changes {
+		fmt.Printf("  %s\n", change)
+	}
+	for _, warning := range m.Warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+
+	if dryRun {
+		fmt.Printf("\n%s", migrated)
+		return nil
+	}
+
+	info, err := os.Stat(hm.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := os.WriteFile(hm.configPath, migrated, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
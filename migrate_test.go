@@ -0,0 +1,253 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		configYAML   string
+		expectedYAML string
+		from         int
+		warnings     []string
+		expectError  bool
+		errorMsg     string
+	}{
+		{
+			name: "legacy scripts are classified",
+			configYAML: `# Project hooks
+
+hooks:
+  pre-commit:
+    # Runs first
+    - name: "format"
+      script: "hooks/format.sh --check" # keeps its arguments
+      description: "Format"
+    - name: "test"
+      script: "go test ./..."
+      description: "Tests"
+    - name: "lint"
+      command: "go vet ./..."
+      description: "Already a command"
+    - name: "unknown"
+      script: "not-a-real-program --flag"
+      description: "Neither"
+settings:
+  verbose: true
+`,
+			expectedYAML: `# Project hooks
+
+# Configuration format version, upgraded by 'hooky migrate'
+version: 3
+hooks:
+  pre-commit:
+    # Runs first
+    - name: "format"
+      script: "hooks/format.sh --check" # keeps its arguments
+      description: "Format"
+    - name: "test"
+      command: "go test ./..."
+      description: "Tests"
+    - name: "lint"
+      command: "go vet ./..."
+      description: "Already a command"
+    - name: "unknown"
+      script: "not-a-real-program --flag"
+      description: "Neither"
+settings:
+  verbose: true
+`,
+			from:     2,
+			warnings: []string{`hooks.pre-commit[3] (unknown): "not-a-real-program --flag" is neither a file nor a command in PATH`},
+		},
+		{
+			name: "hooks_directory is prefixed to scripts",
+			configYAML: `hooks_directory: "hooks"
+hooks:
+  pre-push:
+    - name: "format"
+      script: "format.sh -v"
+    - name: "go"
+      script: "go"
+`,
+			expectedYAML: `# Configuration format version, upgraded by 'hooky migrate'
+version: 3
+hooks:
+  pre-push:
+    - name: "format"
+      script: "hooks/format.sh -v"
+    - name: "go"
+      script: "hooks/go"
+`,
+			from: 1,
+		},
+		{
+			name: "header comment survives removing hooks_directory",
+			configYAML: `# My config
+hooks_directory: scripts
+hooks:
+  pre-push:
+    - name: "go"
+      script: "go"
+`,
+			expectedYAML: `# My config
+
+# Configuration format version, upgraded by 'hooky migrate'
+version: 3
+hooks:
+  pre-push:
+    - name: "go"
+      script: "scripts/go"
+`,
+			from: 1,
+		},
+		{
+			name: "comment above hooks_directory moves to the next key",
+			configYAML: `hooks:
+  pre-push:
+    - name: "go"
+      script: "go"
+# Hook scripts live in scripts/
+hooks_directory: scripts
+settings:
+  verbose: true
+`,
+			expectedYAML: `# Configuration format version, upgraded by 'hooky migrate'
+version: 3
+hooks:
+  pre-push:
+    - name: "go"
+      script: "scripts/go"
+# Hook scripts live in scripts/
+settings:
+  verbose: true
+`,
+			from: 1,
+		},
+		{
+			name: "explicit version is updated",
+			configYAML: `version: 2 # old
+hooks:
+  pre-commit:
+    - name: "test"
+      script: "go test"
+`,
+			expectedYAML: `version: 3 # old
+hooks:
+  pre-commit:
+    - name: "test"
+      command: "go test"
+`,
+			from: 2,
+		},
+		{
+			name: "current version is left alone",
+			configYAML: `version: 3
+hooks:
+  pre-commit:
+    - name: "test"
+      script: "go test"
+`,
+			expectedYAML: `version: 3
+hooks:
+  pre-commit:
+    - name: "test"
+      script: "go test"
+`,
+			from: 3,
+		},
+		{
+			name:        "newer version",
+			configYAML:  "version: 4\n",
+			expectError: true,
+			errorMsg:    "newer than this hooky supports",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := setupRunRepo(t, "")
+			writeFile(t, tmpDir, "hooks/format.sh", "#!/bin/sh\n")
+
+			migrated, m, err := migrateConfig([]byte(tt.configYAML))
+
+			if tt.expectError {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got: %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("migrateConfig failed: %v", err)
+			}
+
+			if string(migrated) != tt.expectedYAML {
+				t.Errorf("Unexpected migrated config:\n%s\nexpected:\n%s", migrated, tt.expectedYAML)
+			}
+			if m.From != tt.from {
+				t.Errorf("Expected to migrate from version %d, got %d", tt.from, m.From)
+			}
+			if len(m.Warnings) != len(tt.warnings) {
+				t.Fatalf("Expected warnings %q, got %q", tt.warnings, m.Warnings)
+			}
+			for i, warning := range tt.warnings {
+				if !strings.HasPrefix(m.Warnings[i], warning) {
+					t.Errorf("Expected warning %q, got %q", warning, m.Warnings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateConfigFile(t *testing.T) {
+	tmpDir := setupRunRepo(t, `hooks:
+  pre-commit:
+    - name: "test"
+      script: "go test ./..."
+      description: "Tests"
+`)
+	configPath := filepath.Join(tmpDir, "hooky.yaml")
+
+	hm := NewHookManager(configPath, false)
+	if err := hm.init(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	if err := hm.validateScripts(); err == nil || !strings.Contains(err.Error(), "run 'hooky migrate'") {
+		t.Errorf("Expected validation to suggest migrating, got: %v", err)
+	}
+
+	if err := hm.MigrateConfig(true); err != nil {
+		t.Fatalf("MigrateConfig --dry-run failed: %v", err)
+	}
+	if content := readFile(t, tmpDir, "hooky.yaml"); strings.Contains(content, "command:") {
+		t.Errorf("Expected --dry-run to leave the file alone, got:\n%s", content)
+	}
+
+	if err := hm.MigrateConfig(false); err != nil {
+		t.Fatalf("MigrateConfig failed: %v", err)
+	}
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig of the migrated file failed: %v", err)
+	}
+	if config.Hooks["pre-commit"][0].Command != "go test ./..." {
+		t.Errorf("Expected the step to become a command, got %+v", config.Hooks["pre-commit"][0])
+	}
+
+	hm = NewHookManager(configPath, false)
+	if err := hm.init(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	if err := hm.validateScripts(); err != nil {
+		t.Errorf("Expected the migrated file to validate, got: %v", err)
+	}
+
+	info, err := os.Stat(configPath)
+	if err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("Expected the file mode to be kept, got %v (%v)", info.Mode(), err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// loadNestedConfigs merges into config the hooks of every configuration file
+// named like configPath in a subdirectory of the repository. Files ignored by
+// git are skipped. Steps from a nested file run in its directory, as if their
+// working_dir were relative to it, so they only run for changes below that
+// directory. Settings of nested files are ignored.
+func loadNestedConfigs(config *Config, configPath string) error {
+	root, err := repoRoot()
+	if err != nil {
+		return fmt.Errorf("failed to find repository root: %w", err)
+	}
+
+	files, err := gitFileList(root, "ls-files", "-z", "--cached", "--others", "--exclude-standard", "--",
+		":(glob)**/"+filepath.Base(configPath))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	rootConfig := canonicalPath(configPath)
+	for _, file := range files {
+		dir := path.Dir(file)
+		if dir == "." || canonicalPath(filepath.Join(root, file)) == rootConfig {
+			continue
+		}
+		// ls-files also lists files that are staged for deletion
+		if _, err := os.Stat(filepath.Join(root, file)); os.IsNotExist(err) {
+			continue
+		}
+
+		nested, err := LoadConfig(filepath.Join(root, file))
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		for hookName, scripts := range nested.Hooks {
+			for _, script := range scripts {
+				script.WorkingDir = path.Join(dir, filepath.ToSlash(script.WorkingDir))
+				script.Source = file
+				if config.Hooks == nil {
+					config.Hooks = make(map[string][]HookScript)
+				}
+				config.Hooks[hookName] = append(config.Hooks[hookName], script)
+			}
+		}
+		config.Files = append(config.Files, file)
+		for source, commit := range nested.Resolved {
+			if config.Resolved == nil {
+				config.Resolved = make(map[string]string)
+			}
+			config.Resolved[source] = commit
+		}
+	}
+
+	return nil
+}
+
+// canonicalPath returns path as an absolute path with symlinks resolved, so
+// that two paths to the same file compare equal.
+func canonicalPath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(p); err == nil {
+		p = resolved
+	}
+	return p
+}
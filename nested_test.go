@@ -0,0 +1,127 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNestedConfigs(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "root"
+      command: "echo root >> calls.txt; true"
+      description: "Root step"
+settings:
+  nested_configs: true
+`)
+
+	writeFile(t, tmpDir, "services/api/hooky.yaml", `
+hooks:
+  pre-commit:
+    - name: "api"
+      command: "echo api >> calls.txt"
+      description: "Runs for API changes"
+      files: "*.go"
+      pass_filenames: true
+  commit-msg:
+    - name: "api-msg"
+      command: "true"
+      description: "Only defined in the nested file"
+settings:
+  stash_unstaged: true
+`)
+	writeFile(t, tmpDir, "web/hooky.yaml", `
+hooks:
+  pre-commit:
+    - name: "web"
+      command: "touch web-ran"
+      description: "Runs for web changes"
+`)
+	writeFile(t, tmpDir, "node_modules/pkg/hooky.yaml", "not: [valid")
+	writeFile(t, tmpDir, ".gitignore", "node_modules/\n")
+	writeFiles(t, tmpDir, "services/api/cmd/main.go")
+	gitRun(t, tmpDir, "add", "services/api/cmd/main.go")
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-commit", nil); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+
+	steps := hm.config.Hooks["pre-commit"]
+	var sources []string
+	for _, step := range steps {
+		sources = append(sources, step.Name+"="+step.Source+":"+step.WorkingDir)
+	}
+	expected := "root=hooky.yaml: api=services/api/hooky.yaml:services/api web=web/hooky.yaml:web"
+	if got := strings.Join(sources, " "); got != expected {
+		t.Errorf("Expected merged steps %q, got %q", expected, got)
+	}
+
+	if len(hm.config.Hooks["commit-msg"]) != 1 {
+		t.Error("Expected hooks defined only in a nested file to be merged")
+	}
+	if hm.config.Settings.StashUnstaged {
+		t.Error("Settings from nested files should be ignored")
+	}
+
+	if content := readFile(t, tmpDir, "calls.txt"); content != "root\n" {
+		t.Errorf("Expected the root step to run from the root, got %q", content)
+	}
+	if content := readFile(t, tmpDir, "services/api/calls.txt"); content != "api cmd/main.go\n" {
+		t.Errorf("Expected the nested step to run in its directory, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "web", "web-ran")); !os.IsNotExist(err) {
+		t.Error("Nested steps should be skipped when nothing changed below their directory")
+	}
+}
+
+func TestNestedConfigsInvalidFile(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "root"
+      command: "true"
+      description: "Root step"
+settings:
+  nested_configs: true
+`)
+	writeFile(t, tmpDir, "services/api/hooky.yaml", `
+hooks:
+  pre-commit:
+    - name: "broken"
+      description: "Has neither script nor command"
+`)
+
+	err := NewHookManager("hooky.yaml", false).RunHook("pre-commit", nil)
+	if err == nil || !strings.Contains(err.Error(), "services/api/hooky.yaml") {
+		t.Errorf("Expected an error naming the nested file, got: %v", err)
+	}
+}
+
+func TestNestedConfigsDisabled(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "root"
+      command: "true"
+      description: "Root step"
+`)
+	writeFile(t, tmpDir, "services/api/hooky.yaml", `
+hooks:
+  pre-commit:
+    - name: "api"
+      command: "true"
+      description: "Not merged"
+`)
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-commit", nil); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+	if len(hm.config.Hooks["pre-commit"]) != 1 {
+		t.Error("Nested files should only be merged with nested_configs enabled")
+	}
+}
@@ -0,0 +1,69 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// maxArgLength is the number of bytes hooky assumes it may use for command
+// line arguments and environment. Linux allows more, but 128 KiB is safe on
+// every Unix hooky supports.
+var maxArgLength = 128 * 1024
+
+// envSharesArgSpace reports whether the environment counts towards
+// maxArgLength, as it does for execve.
+const envSharesArgSpace = true
+
+// setProcessGroup starts cmd in its own process group so that everything it
+// spawns can be killed together. A background group stops when it reads the
+// terminal, so when cmd's stdin is hooky's controlling terminal the group is
+// made the terminal's foreground group instead; the returned function hands
+// the terminal back to hooky once cmd has exited.
+func setProcessGroup(cmd *exec.Cmd) (restore func()) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	tty, ok := cmd.Stdin.(*os.File)
+	if !ok {
+		return func() {}
+	}
+	fd := int(tty.Fd())
+	// Only take over a terminal whose foreground group is hooky's own
+	if pgrp, err := foregroundGroup(fd); err != nil || pgrp != syscall.Getpgrp() {
+		return func() {}
+	}
+
+	cmd.SysProcAttr.Foreground = true
+	cmd.SysProcAttr.Ctty = fd
+	return func() {
+		// hooky is now in the background itself, where changing the
+		// foreground group raises SIGTTOU
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+		pgrp := int32(syscall.Getpgrp())
+		syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(syscall.TIOCSPGRP), uintptr(unsafe.Pointer(&pgrp)))
+	}
+}
+
+// foregroundGroup returns the foreground process group of the terminal fd.
+// It fails if fd is not a terminal.
+func foregroundGroup(fd int) (int, error) {
+	var pgrp int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(syscall.TIOCGPGRP), uintptr(unsafe.Pointer(&pgrp))); errno != 0 {
+		return 0, errno
+	}
+	return int(pgrp), nil
+}
+
+// killProcessGroup kills cmd and, if it was started with setProcessGroup,
+// every process in its group.
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr != nil && cmd.SysProcAttr.Setpgid {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,34 @@
+//go:build windows
+
+package main
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// maxArgLength is the number of bytes hooky assumes it may use for command
+// line arguments. Windows limits a command line to 32767 characters.
+var maxArgLength = 32 * 1024
+
+// envSharesArgSpace reports whether the environment counts towards
+// maxArgLength. On Windows it is passed separately from the command line.
+const envSharesArgSpace = false
+
+// setProcessGroup starts cmd in a new process group so that everything it
+// spawns can be killed together. The console stays shared with hooky, so
+// there is nothing to restore afterwards.
+func setProcessGroup(cmd *exec.Cmd) (restore func()) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+	return func() {}
+}
+
+// killProcessGroup kills cmd and its whole process tree.
+func killProcessGroup(cmd *exec.Cmd) error {
+	kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+	if err := kill.Run(); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// refUpdate is one line of the input git passes to pre-push: a local ref
+// and the remote ref it updates, each with its object name.
+type refUpdate struct {
+	LocalRef  string
+	LocalSHA  string
+	RemoteRef string
+	RemoteSHA string
+}
+
+// deletes reports whether the update deletes the remote ref.
+func (u refUpdate) deletes() bool {
+	return isZeroSHA(u.LocalSHA)
+}
+
+// creates reports whether the update creates the remote ref.
+func (u refUpdate) creates() bool {
+	return isZeroSHA(u.RemoteSHA)
+}
+
+func isZeroSHA(sha string) bool {
+	return strings.Trim(sha, "0") == ""
+}
+
+// parseRefUpdates parses pre-push input, one
+// "<local ref> <local sha> <remote ref> <remote sha>" line per ref.
+func parseRefUpdates(input []byte) ([]refUpdate, error) {
+	var updates []refUpdate
+	for _, line := range strings.Split(string(input), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("malformed ref update %q", line)
+		}
+		updates = append(updates, refUpdate{
+			LocalRef:  fields[0],
+			LocalSHA:  fields[1],
+			RemoteRef: fields[2],
+			RemoteSHA: fields[3],
+		})
+	}
+	return updates, nil
+}
+
+// updatedFiles returns the files touched by the commits that updates add to
+// remote. For a ref the remote does not have yet, those are the commits no
+// branch of the remote contains; the same goes for a ref whose current
+// remote commit is not available locally.
+func updatedFiles(remote string, updates []refUpdate) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, update := range updates {
+		if update.deletes() {
+			continue
+		}
+
+		args := []string{"log", "--format=", "--name-only", "--no-renames", "--diff-filter=ACMR", "-z", update.LocalSHA, "--not"}
+		if !update.creates() && hasObject(update.RemoteSHA) {
+			args = append(args, update.RemoteSHA)
+		} else {
+			args = append(args, remoteRefs(remote))
+		}
+
+		updated, err := gitFileList("", args...)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range updated {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// remoteRefs returns the rev-list option selecting the remote-tracking
+// branches of remote, or of every remote if it is empty.
+func remoteRefs(remote string) string {
+	if remote == "" {
+		return "--remotes"
+	}
+	return "--remotes=" + remote
+}
+
+// hasObject reports whether sha names an object in the local repository.
+func hasObject(sha string) bool {
+	_, err := runGit("", "cat-file", "-e", sha+"^{commit}")
+	return err == nil
+}
+
+// matchRefs reports whether any of updates pushes to a remote ref matching
+// one of patterns.
+func matchRefs(patterns Patterns, updates []refUpdate) bool {
+	for _, update := range updates {
+		if matchAny(patterns, update.RemoteRef) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRefUpdates(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    []refUpdate
+		expectError bool
+	}{
+		{
+			name:  "one line per ref",
+			input: "refs/heads/main 1111 refs/heads/main 2222\nrefs/heads/dev 3333 refs/heads/develop 0000\n",
+			expected: []refUpdate{
+				{LocalRef: "refs/heads/main", LocalSHA: "1111", RemoteRef: "refs/heads/main", RemoteSHA: "2222"},
+				{LocalRef: "refs/heads/dev", LocalSHA: "3333", RemoteRef: "refs/heads/develop", RemoteSHA: "0000"},
+			},
+		},
+		{
+			name:  "deletion",
+			input: "(delete) 0000000000000000000000000000000000000000 refs/heads/old 4444\n",
+			expected: []refUpdate{
+				{LocalRef: "(delete)", LocalSHA: "0000000000000000000000000000000000000000", RemoteRef: "refs/heads/old", RemoteSHA: "4444"},
+			},
+		},
+		{
+			name:     "empty input",
+			input:    "",
+			expected: nil,
+		},
+		{
+			name:        "malformed line",
+			input:       "refs/heads/main 1111\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates, err := parseRefUpdates([]byte(tt.input))
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected an error, got %v", updates)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(updates, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, updates)
+			}
+		})
+	}
+}
+
+func TestPrePushInput(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-push:
+    - name: "main"
+      command: "echo main >> log; true"
+      description: "Only for pushes to main"
+      only_refs: refs/heads/main
+    - name: "release"
+      command: "echo release >> log; true"
+      description: "Only for release branches"
+      only_refs: "refs/heads/release/**"
+    - name: "go"
+      command: "echo go >> log; true"
+      description: "Only for pushed Go files"
+      files: "*.go"
+    - name: "docs"
+      command: "echo docs >> log; true"
+      description: "Only for pushed Markdown files"
+      files: "*.md"
+    - name: "first"
+      command: "cat > first.txt; true"
+      description: "Reads the input in parallel"
+      parallel: true
+    - name: "second"
+      command: "cat > second.txt; true"
+      description: "Reads the input in parallel"
+      parallel: true
+`)
+	remote := t.TempDir()
+	gitRun(t, remote, "init", "--bare")
+	gitRun(t, tmpDir, "remote", "add", "origin", remote)
+
+	writeFiles(t, tmpDir, "README.md")
+	gitRun(t, tmpDir, "add", "README.md")
+	gitRun(t, tmpDir, "commit", "-m", "base")
+	gitRun(t, tmpDir, "push", "origin", "HEAD:refs/heads/main")
+	base := strings.TrimSpace(gitRun(t, tmpDir, "rev-parse", "HEAD"))
+
+	writeFiles(t, tmpDir, "app.go")
+	gitRun(t, tmpDir, "add", "app.go")
+	gitRun(t, tmpDir, "commit", "-m", "app")
+	head := strings.TrimSpace(gitRun(t, tmpDir, "rev-parse", "HEAD"))
+	zero := strings.Repeat("0", 40)
+
+	tests := []struct {
+		name        string
+		input       string
+		expectedLog string
+	}{
+		{
+			name:        "update of main",
+			input:       "refs/heads/main " + head + " refs/heads/main " + base + "\n",
+			expectedLog: "main\ngo\n",
+		},
+		{
+			name:        "new release branch",
+			input:       "refs/heads/main " + head + " refs/heads/release/1.0 " + zero + "\n",
+			expectedLog: "release\ngo\n",
+		},
+		{
+			name:        "deletion pushes no files",
+			input:       "(delete) " + zero + " refs/heads/main " + base + "\n",
+			expectedLog: "main\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(filepath.Join(tmpDir, "log"))
+			withStdin(t, tt.input)
+
+			if err := NewHookManager("hooky.yaml", false).RunHook("pre-push", []string{"origin", remote}); err != nil {
+				t.Fatalf("RunHook failed: %v", err)
+			}
+			if log := readFile(t, tmpDir, "log"); log != tt.expectedLog {
+				t.Errorf("Expected log %q, got %q", tt.expectedLog, log)
+			}
+			for _, name := range []string{"first.txt", "second.txt"} {
+				if content := readFile(t, tmpDir, name); content != tt.input {
+					t.Errorf("Expected %s to receive the hook input, got %q", name, content)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// remoteManifest is the file in a hook repository that defines the steps
+// other repositories can use.
+const remoteManifest = "hooky-hooks.yaml"
+
+// remoteRepo references step definitions in another git repository, pinned
+// to a revision. Each step under Hooks names a definition from the
+// repository's manifest; any other fields it sets override the definition.
+type remoteRepo struct {
+	Repo  string                 `yaml:"repo"`
+	Rev   string                 `yaml:"rev"`
+	Hooks map[string][]yaml.Node `yaml:"hooks"`
+}
+
+// resolveRemoteRepo returns the steps repo contributes to each hook. Script
+// paths of the definitions are resolved to a checkout of the pinned
+// revision in the cache, so steps can be validated and run like local ones.
+func resolveRemoteRepo(repo remoteRepo) (map[string][]HookScript, error) {
+	if repo.Repo == "" || repo.Rev == "" {
+		return nil, fmt.Errorf("repos entries need both repo and rev")
+	}
+	source := fmt.Sprintf("%s@%s", repo.Repo, repo.Rev)
+
+	// Revisions are pinned, so a cached copy never needs fetching again
+	checkout, err := checkoutRepo(repo.Repo, repo.Rev)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(checkout, remoteManifest))
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read %s: %w", source, remoteManifest, err)
+	}
+
+	var manifest struct {
+		Hooks []HookScript `yaml:"hooks"`
+	}
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%s: failed to parse %s: %w", source, remoteManifest, err)
+	}
+
+	definitions := make(map[string]HookScript, len(manifest.Hooks))
+	for _, definition := range manifest.Hooks {
+		if definition.Script != "" {
+			script, err := remoteScriptPath(checkout, definition.Script)
+			if err != nil {
+				return nil, fmt.Errorf("%s: hook %q in %s: %w", source, definition.Name, remoteManifest, err)
+			}
+			definition.Script = script
+		}
+		definition.Source = source
+		definitions[definition.Name] = definition
+	}
+
+	hooks := make(map[string][]HookScript)
+	for hookName, nodes := range repo.Hooks {
+		for _, node := range nodes {
+			var ref struct {
+				Name string `yaml:"name"`
+			}
+			if err := node.Decode(&ref); err != nil {
+				return nil, fmt.Errorf("%s: line %d: %w", source, node.Line, err)
+			}
+
+			step, ok := definitions[ref.Name]
+			if !ok {
+				return nil, fmt.Errorf("%s: no hook named %q in %s (available: %s)", source, ref.Name, remoteManifest, strings.Join(definitionNames(definitions), ", "))
+			}
+
+			// Fields set in the referencing file override the definition; env
+			// adds to its variables, in a copy shared with no other reference
+			step.Env = maps.Clone(step.Env)
+			if err := node.Decode(&step); err != nil {
+				return nil, fmt.Errorf("%s: line %d: %w", source, node.Line, err)
+			}
+			hooks[hookName] = append(hooks[hookName], step)
+		}
+	}
+
+	return hooks, nil
+}
+
+// remoteScriptPath makes the script file of a "script" line, which is
+// relative to the hook repository, an absolute path into checkout.
+func remoteScriptPath(checkout, script string) (string, error) {
+	fields := strings.Fields(script)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("script is blank")
+	}
+	if filepath.IsAbs(fields[0]) {
+		return script, nil
+	}
+	fields[0] = filepath.ToSlash(filepath.Join(checkout, filepath.FromSlash(fields[0])))
+	return strings.Join(fields, " "), nil
+}
+
+func definitionNames(definitions map[string]HookScript) []string {
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkoutRepo returns a directory holding the files of url at rev,
+// fetching and checking them out into the cache the first time. Checkouts
+// are keyed by commit, so they are shared by every project using the same
+// revision.
+func checkoutRepo(url, rev string) (string, error) {
+	cache, err := fetchRepo(url, rev, false)
+	if err != nil {
+		return "", err
+	}
+
+	commit, err := cachedCommit(cache, rev)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a commit in %s: %w", rev, url, err)
+	}
+
+	cacheDir, err := hookyCacheDir()
+	if err != nil {
+		return "", err
+	}
+	checkout := filepath.Join(cacheDir, "checkouts", filepath.Base(cache)+"-"+commit[:12])
+	if _, err := os.Stat(checkout); err == nil {
+		return checkout, nil
+	}
+
+	// Check out into a temporary directory and rename it into place, so that
+	// hooks running concurrently never see a partial checkout
+	if err := os.MkdirAll(filepath.Dir(checkout), 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(checkout), ".checkout-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	cmd := exec.Command("git", "--git-dir="+cache, "--work-tree="+tmp, "checkout", "--quiet", commit, "--", ".")
+	// Use a throwaway index so concurrent checkouts do not share state
+	cmd.Env = append(os.Environ(), "GIT_INDEX_FILE="+filepath.Join(tmp, ".git-index"))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to check out %s of %s: %w: %s", rev, url, err, strings.TrimSpace(stderr.String()))
+	}
+	os.Remove(filepath.Join(tmp, ".git-index"))
+
+	if err := os.Rename(tmp, checkout); err != nil {
+		if _, statErr := os.Stat(checkout); statErr == nil {
+			// Another hooky process finished the same checkout first
+			return checkout, nil
+		}
+		return "", fmt.Errorf("failed to move checkout into the cache: %w", err)
+	}
+	return checkout, nil
+}
+
+// hookyCacheDir returns the directory where hooky caches data fetched from
+// other repositories. HOOKY_CACHE_DIR overrides the per-user default.
+func hookyCacheDir() (string, error) {
+	if dir := os.Getenv("HOOKY_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find cache directory (set HOOKY_CACHE_DIR): %w", err)
+	}
+	return filepath.Join(dir, "hooky"), nil
+}
+
+// fetchRepo makes ref of the git repository at url available in a bare
+// repository in the cache and returns the cache's path. A ref that was
+// fetched before is only fetched again with refresh, so that hooks do not
+// need the network every time they run.
+func fetchRepo(url, ref string, refresh bool) (string, error) {
+	cacheDir, err := hookyCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(url))
+	cache := filepath.Join(cacheDir, "repos", hex.EncodeToString(sum[:8]))
+
+	if _, err := os.Stat(cache); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(cache), 0755); err != nil {
+			return "", fmt.Errorf("failed to create cache directory: %w", err)
+		}
+		if _, err := runGit("", "init", "--quiet", "--bare", cache); err != nil {
+			return "", err
+		}
+	}
+
+	if !refresh {
+		if _, err := runGit("", "--git-dir="+cache, "rev-parse", "--verify", "--quiet", cachedRef(ref)); err == nil {
+			return cache, nil
+		}
+	}
+
+	if _, err := runGit("", "--git-dir="+cache, "fetch", "--quiet", "--depth=1", url, ref); err != nil {
+		return "", fmt.Errorf("failed to fetch %s from %s: %w", ref, url, err)
+	}
+	if _, err := runGit("", "--git-dir="+cache, "update-ref", cachedRef(ref), "FETCH_HEAD"); err != nil {
+		return "", err
+	}
+	return cache, nil
+}
+
+// cachedCommit returns the commit a ref fetched into cache points to.
+func cachedCommit(cache, ref string) (string, error) {
+	commit, err := runGit("", "--git-dir="+cache, "rev-parse", "--verify", cachedRef(ref)+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(commit), nil
+}
+
+// cachedRef returns the ref under which a fetched ref is kept in the cache.
+func cachedRef(ref string) string {
+	sum := sha256.Sum256([]byte(ref))
+	return "refs/hooky/" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const remoteHooksManifest = `
+hooks:
+  - name: "check"
+    script: "bin/check.sh --strict"
+    description: "Records the files it checks"
+    files: "*.go"
+    pass_filenames: true
+  - name: "echo"
+    command: "echo remote"
+    description: "A command from the hook repository"
+`
+
+func TestRemoteRepos(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("HOOKY_CACHE_DIR", cacheDir)
+
+	url := setupSharedRepo(t, map[string]string{
+		"hooky-hooks.yaml": remoteHooksManifest,
+		"bin/check.sh":     "#!/bin/sh\necho checked \"$@\" >> checked.txt\n",
+	})
+
+	tmpDir := setupRunRepo(t, `
+repos:
+  - repo: "`+url+`"
+    rev: v1
+    hooks:
+      pre-commit:
+        - name: "check"
+          files: "*.txt"
+`)
+	writeFiles(t, tmpDir, "a.txt", "b.go")
+	gitRun(t, tmpDir, "add", "a.txt", "b.go")
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-commit", nil); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+
+	// The step runs in this repository, with the override of files applied
+	if content := readFile(t, tmpDir, "checked.txt"); content != "checked --strict a.txt\n" {
+		t.Errorf("Expected the remote script to check a.txt, got %q", content)
+	}
+
+	step := hm.config.Hooks["pre-commit"][0]
+	if !strings.HasPrefix(step.Script, filepath.ToSlash(cacheDir)) {
+		t.Errorf("Expected the script to resolve into the cache, got %q", step.Script)
+	}
+	if step.Source != url+"@v1" {
+		t.Errorf("Expected the step's source to be the remote repository, got %q", step.Source)
+	}
+	if err := hm.validateScripts(); err != nil {
+		t.Errorf("Expected cached remote scripts to validate, got: %v", err)
+	}
+
+	// Once cached, the repository is not needed any more
+	if err := os.RemoveAll(strings.TrimPrefix(url, "file://")); err != nil {
+		t.Fatalf("Failed to remove hook repository: %v", err)
+	}
+	os.Remove(filepath.Join(tmpDir, "checked.txt"))
+
+	if err := NewHookManager("hooky.yaml", false).RunHook("pre-commit", nil); err != nil {
+		t.Fatalf("RunHook failed offline: %v", err)
+	}
+	if content := readFile(t, tmpDir, "checked.txt"); content != "checked --strict a.txt\n" {
+		t.Errorf("Expected the cached script to run offline, got %q", content)
+	}
+}
+
+func TestRemoteReposErrors(t *testing.T) {
+	t.Setenv("HOOKY_CACHE_DIR", t.TempDir())
+
+	url := setupSharedRepo(t, map[string]string{
+		"hooky-hooks.yaml": remoteHooksManifest,
+		"bin/check.sh":     "#!/bin/sh\n",
+	})
+	blankURL := setupSharedRepo(t, map[string]string{
+		"hooky-hooks.yaml": "hooks:\n  - name: \"blank\"\n    script: \"  \"\n",
+	})
+
+	tests := []struct {
+		name     string
+		repos    string
+		errorMsg string
+	}{
+		{
+			name: "unknown hook",
+			repos: `
+  - repo: "` + url + `"
+    rev: v1
+    hooks:
+      pre-commit:
+        - name: "missing"
+`,
+			errorMsg: `no hook named "missing" in hooky-hooks.yaml (available: check, echo)`,
+		},
+		{
+			name: "unknown revision",
+			repos: `
+  - repo: "` + url + `"
+    rev: v2
+    hooks:
+      pre-commit:
+        - name: "check"
+`,
+			errorMsg: "failed to fetch v2",
+		},
+		{
+			name: "missing rev",
+			repos: `
+  - repo: "` + url + `"
+    hooks:
+      pre-commit:
+        - name: "check"
+`,
+			errorMsg: "need both repo and rev",
+		},
+		{
+			name: "blank script in the manifest",
+			repos: `
+  - repo: "` + blankURL + `"
+    rev: v1
+    hooks:
+      pre-commit:
+        - name: "blank"
+`,
+			errorMsg: `@v1: hook "blank" in hooky-hooks.yaml: script is blank`,
+		},
+		{
+			name: "invalid override",
+			repos: `
+  - repo: "` + url + `"
+    rev: v1
+    hooks:
+      pre-push:
+        - name: "check"
+          fixer: true
+`,
+			errorMsg: "@v1: hook pre-push[0] (check): fixer is only supported for pre-commit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			writeFile(t, tmpDir, "hooky.yaml", "repos:"+tt.repos)
+
+			_, err := LoadConfig(filepath.Join(tmpDir, "hooky.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Expected error containing %q, got: %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,512 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// errInterrupted is returned when a hook is stopped by SIGINT or SIGTERM.
+var errInterrupted = errors.New("interrupted")
+
+// StepError records why a single step failed.
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Step, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError is returned when a step runs longer than its timeout.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s", e.Timeout)
+}
+
+// HookError is returned by RunHook when one or more steps fail.
+type HookError struct {
+	Failed []*StepError
+	Total  int
+}
+
+func (e *HookError) Error() string {
+	names := make([]string, len(e.Failed))
+	for i, failed := range e.Failed {
+		names[i] = failed.Step
+	}
+	return fmt.Sprintf("%d of %d steps failed: %s", len(e.Failed), e.Total, strings.Join(names, ", "))
+}
+
+func (e *HookError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, failed := range e.Failed {
+		errs[i] = failed
+	}
+	return errs
+}
+
+// hookRun holds the state shared by the steps of one hook invocation.
+type hookRun struct {
+	hm       *HookManager
+	hookName string
+	args     []string
+	root     string
+
+	// files lists the changed files the hook is about, for hooks that have
+	// such a list (see changedFiles). filesList is the path of a copy of it
+	// that steps find in HOOKY_STAGED_FILES or HOOKY_PUSHED_FILES.
+	files     []string
+	hasFiles  bool
+	filesList string
+
+	// stdin holds the hook's standard input, read once and replayed to
+	// every step, for hooks that receive one.
+	stdin       []byte
+	replayStdin bool
+
+	// updates are the ref updates parsed from pre-push's input, when it
+	// could be read.
+	updates    []refUpdate
+	hasUpdates bool
+
+	// stashed lists the files whose unstaged changes stash_unstaged set
+	// aside; fixers must not restage them.
+	stashed []string
+
+	// condition is what if: expressions are evaluated against, set up by
+	// the first step that has one.
+	condition *conditionContext
+}
+
+// RunHook executes the steps configured for hookName, forwarding the
+// arguments git passed to the hook. The configuration is loaded at call
+// time, so edits to hooky.yaml take effect without reinstalling.
+//
+// Consecutive steps marked parallel run concurrently; every other step runs
+// on its own, in configuration order. With the stash_unstaged setting,
+// pre-commit steps run against the staged content only.
+func (hm *HookManager) RunHook(hookName string, args []string) (err error) {
+	if err := hm.init(); err != nil {
+		return err
+	}
+
+	if err := hm.checkLock(hm.config.Hooks[hookName]); err != nil {
+		return err
+	}
+
+	scripts := hm.withChainedHook(hookName, hm.config.Hooks[hookName])
+	if len(scripts) == 0 {
+		if hm.config.Settings.Verbose {
+			fmt.Printf("No scripts configured for hook: %s\n", hookName)
+		}
+		return nil
+	}
+
+	root, err := repoRoot()
+	if err != nil {
+		return fmt.Errorf("failed to find repository root: %w", err)
+	}
+
+	run := &hookRun{hm: hm, hookName: hookName, args: args, root: root}
+
+	// Steps would otherwise compete for the input; when run by hand from a
+	// terminal there is none to read
+	if receivesStdin(hookName) && !isTerminal(os.Stdin) {
+		run.stdin, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read hook input: %w", err)
+		}
+		run.replayStdin = true
+
+		if hookName == "pre-push" {
+			updates, err := parseRefUpdates(run.stdin)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Ignoring pre-push input: %v\n", err)
+			} else {
+				run.updates, run.hasUpdates = updates, true
+			}
+		}
+	}
+
+	// Diffing every pushed ref takes a while, so pre-push only looks up its
+	// files when a step filters by them, calls changed() or sets file_list;
+	// the staged files of pre-commit are cheap to list
+	lookUp := hookName == "pre-commit"
+	var usesFiles bool
+	for _, script := range scripts {
+		usesFiles = usesFiles || script.usesFiles()
+		lookUp = lookUp || script.FileList || callsFunction(script.If, "changed")
+	}
+	if hasChangedFiles(hookName) && (lookUp || usesFiles) {
+		files, _, err := changedFiles(hookName, args, run.updates)
+		if err != nil {
+			// Only steps that select files cannot do without them; for the
+			// others changed() is false and the list variable is not set
+			if usesFiles {
+				return fmt.Errorf("failed to determine changed files: %w", err)
+			}
+		} else {
+			run.files, run.hasFiles = files, true
+			run.filesList, err = writeFileList(files)
+			if err != nil {
+				return fmt.Errorf("failed to write changed file list: %w", err)
+			}
+			defer os.Remove(run.filesList)
+		}
+	}
+
+	// Stop running steps rather than orphaning them when the user hits Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if hookName == "pre-commit" && hm.config.Settings.StashUnstaged {
+		// Each worktree has its own index, so its stash lives in its own git
+		// dir. err is assigned rather than declared so that the deferred
+		// restore reports to the caller.
+		var gitDir string
+		gitDir, err = worktreeGitDir()
+		if err != nil {
+			return fmt.Errorf("failed to find git directory: %w", err)
+		}
+		var stash *unstagedStash
+		stash, err = stashUnstaged(root, gitDir)
+		if err != nil {
+			return fmt.Errorf("failed to stash unstaged changes: %w", err)
+		}
+		if stash != nil {
+			run.stashed = stash.files
+			// Restore even when a step fails or the user interrupts the hook
+			defer func() {
+				if restoreErr := stash.restore(); restoreErr != nil {
+					err = errors.Join(err, restoreErr)
+				}
+			}()
+		}
+	}
+
+	return run.runSteps(ctx, scripts)
+}
+
+// runSteps runs scripts group by group, stopping after the first group with
+// a failure.
+func (r *hookRun) runSteps(ctx context.Context, scripts []HookScript) error {
+	for _, group := range groupSteps(scripts) {
+		var failed []*StepError
+		if len(group) == 1 {
+			if err := r.runSequential(ctx, group[0]); err != nil {
+				failed = append(failed, &StepError{Step: group[0].Name, Err: err})
+			}
+		} else {
+			failed = r.runParallel(ctx, group)
+		}
+
+		if ctx.Err() != nil {
+			fmt.Printf("Hook interrupted\n")
+			return errInterrupted
+		}
+
+		if len(failed) > 0 {
+			for _, stepErr := range failed {
+				var timeoutErr *TimeoutError
+				if errors.As(stepErr, &timeoutErr) {
+					fmt.Printf("Hook failed: %s (%s)\n", stepErr.Step, timeoutErr)
+				} else {
+					fmt.Printf("Hook failed: %s\n", stepErr.Step)
+				}
+			}
+			return &HookError{Failed: failed, Total: len(scripts)}
+		}
+	}
+
+	return nil
+}
+
+// groupSteps splits scripts into runs of consecutive parallel steps, with
+// every sequential step in a group of its own.
+func groupSteps(scripts []HookScript) [][]HookScript {
+	var groups [][]HookScript
+	for i, script := range scripts {
+		if script.Parallel && i > 0 && scripts[i-1].Parallel {
+			groups[len(groups)-1] = append(groups[len(groups)-1], script)
+			continue
+		}
+		groups = append(groups, []HookScript{script})
+	}
+	return groups
+}
+
+// shouldRun reports whether script has anything to do in this run. Steps
+// are skipped when their if: expression is false, when they have only_refs
+// and no pushed ref matches, and when they use files and no changed file
+// matches their patterns; when the refs or the changed files are unknown,
+// the patterns do not apply.
+func (r *hookRun) shouldRun(script HookScript) bool {
+	if script.If != "" {
+		// An env file that cannot be read fails the step once it runs
+		vars, _ := mergeStepVars(r.hm.config.Settings, script)
+		if run, reason := evalCondition(script, r.conditionContext().withVars(vars)); !run {
+			fmt.Printf("Skipped: %s (%s)\n", script.Name, reason)
+			return false
+		}
+	}
+
+	if len(script.OnlyRefs) > 0 && r.hasUpdates && !matchRefs(script.OnlyRefs, r.updates) {
+		fmt.Printf("Skipped: %s (no matching refs)\n", script.Name)
+		return false
+	}
+
+	if !script.usesFiles() || !r.hasFiles {
+		return true
+	}
+
+	if len(r.stepFiles(script)) == 0 {
+		fmt.Printf("Skipped: %s (no matching files)\n", script.Name)
+		return false
+	}
+	return true
+}
+
+// conditionContext returns the context for if: expressions in this run.
+func (r *hookRun) conditionContext() *conditionContext {
+	if r.condition == nil {
+		r.condition = newConditionContext(r.hookName)
+		if r.hookName == "pre-push" && len(r.args) > 0 {
+			r.condition.Remote = r.args[0]
+		}
+		r.condition.Files, r.condition.HasFiles = r.files, r.hasFiles
+		r.condition.Updates, r.condition.HasUpdates = r.updates, r.hasUpdates
+	}
+	return r.condition
+}
+
+// stepFiles returns the changed files matching script's patterns. For steps
+// with a working_dir, only files below it are considered, and both the
+// patterns and the returned paths are relative to it.
+func (r *hookRun) stepFiles(script HookScript) []string {
+	return filterFiles(filesUnder(r.files, script.Dir()), script.Files, script.Exclude)
+}
+
+// runSequential runs a single step with direct access to the terminal.
+func (r *hookRun) runSequential(ctx context.Context, script HookScript) error {
+	if !r.shouldRun(script) {
+		return nil
+	}
+
+	if script.Description != "" && r.hm.config.Settings.Verbose {
+		fmt.Printf("# %s\n", script.Description)
+	}
+	fmt.Printf("Running: %s\n", script.Name)
+
+	if script.Fixer {
+		return r.runFixer(ctx, script, r.input(), os.Stdout, os.Stderr)
+	}
+	return r.runScript(ctx, script, r.input(), os.Stdout, os.Stderr)
+}
+
+// input returns the standard input for a sequential step: a fresh copy of
+// the hook's input when it is replayed, the process's stdin otherwise.
+func (r *hookRun) input() io.Reader {
+	if r.replayStdin {
+		return bytes.NewReader(r.stdin)
+	}
+	return os.Stdin
+}
+
+// isTerminal reports whether f is a terminal, or another character device
+// such as /dev/null, rather than a pipe or file that git writes input to.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// receivesStdin reports whether git passes data to hookName on stdin.
+func receivesStdin(hookName string) bool {
+	switch hookName {
+	case "pre-push", "pre-receive", "post-receive", "post-rewrite":
+		return true
+	}
+	return false
+}
+
+// runParallel runs scripts concurrently, limited by the parallel_workers
+// setting. Each step's output is buffered and printed as one block when the
+// step finishes. It returns the failed steps in configuration order.
+func (r *hookRun) runParallel(ctx context.Context, group []HookScript) []*StepError {
+	var scripts []HookScript
+	for _, script := range group {
+		if r.shouldRun(script) {
+			scripts = append(scripts, script)
+		}
+	}
+	if len(scripts) == 0 {
+		return nil
+	}
+
+	workers := r.hm.config.Settings.ParallelWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	names := make([]string, len(scripts))
+	for i, script := range scripts {
+		names[i] = script.Name
+	}
+	fmt.Printf("Running in parallel: %s\n", strings.Join(names, ", "))
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errs   = make([]error, len(scripts))
+		tokens = make(chan struct{}, workers)
+	)
+
+	for i, script := range scripts {
+		wg.Add(1)
+		go func(i int, script HookScript) {
+			defer wg.Done()
+			tokens <- struct{}{}
+			defer func() { <-tokens }()
+
+			var stdin io.Reader
+			if r.replayStdin {
+				stdin = bytes.NewReader(r.stdin)
+			}
+			var output bytes.Buffer
+			errs[i] = r.runScript(ctx, script, stdin, &output, &output)
+
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Printf("Running: %s\n", script.Name)
+			os.Stdout.Write(output.Bytes())
+		}(i, script)
+	}
+	wg.Wait()
+
+	var failed []*StepError
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, &StepError{Step: scripts[i].Name, Err: err})
+		}
+	}
+	return failed
+}
+
+// runScript runs one step to completion. A step normally runs once with the
+// hook's arguments; with pass_filenames it instead receives its matching
+// files, split over as many invocations as the OS argument limit requires.
+// The step's timeout covers all of its invocations.
+func (r *hookRun) runScript(ctx context.Context, script HookScript, stdin io.Reader, stdout, stderr io.Writer) error {
+	timeout, err := r.hm.stepTimeout(script)
+	if err != nil {
+		return err
+	}
+
+	stepCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		stepCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	env, err := r.hm.stepEnviron(script)
+	if err != nil {
+		fmt.Fprintf(stderr, "Step %s cannot run: %v\n", script.Name, err)
+		return err
+	}
+	env = appendVars(env, r.hookVars(script))
+
+	// Batches are sized for the environment the step really gets
+	line := script.CommandLine()
+	invocations := [][]string{r.args}
+	if script.PassFilenames {
+		invocations = batchFiles(r.stepFiles(script), argLimit(line, env))
+	}
+
+	var firstErr error
+	for _, args := range invocations {
+		cmd := shellCommand(line, args)
+		cmd.Dir = filepath.Join(r.root, script.Dir())
+		cmd.Env = env
+		err := runProcess(stepCtx, cmd, timeout > 0, stdin, stdout, stderr)
+		switch {
+		case ctx.Err() != nil:
+			return errInterrupted
+		case stepCtx.Err() != nil:
+			fmt.Fprintf(stderr, "Step %s timed out after %s, killed it\n", script.Name, timeout)
+			return &TimeoutError{Timeout: timeout}
+		case err != nil && firstErr == nil:
+			firstErr = err
+		}
+		// Only the first invocation can consume the hook's stdin
+		stdin = nil
+	}
+	return firstErr
+}
+
+// runProcess runs cmd until it exits or ctx is done, in which case cmd is
+// killed. With ownGroup, cmd runs in its own process group, which is killed
+// as a whole so that children such as test binaries do not outlive it, and
+// which gets the terminal while it runs.
+func runProcess(ctx context.Context, cmd *exec.Cmd, ownGroup bool, stdin io.Reader, stdout, stderr io.Writer) error {
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Bound the wait for output pipes held open by killed grandchildren
+	cmd.WaitDelay = 5 * time.Second
+	if ownGroup {
+		restore := setProcessGroup(cmd)
+		defer restore()
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		killProcessGroup(cmd)
+		<-done
+		return ctx.Err()
+	}
+}
+
+// stepTimeout returns the timeout for script, falling back to the default
+// from settings. Zero means the step may run indefinitely.
+func (hm *HookManager) stepTimeout(script HookScript) (time.Duration, error) {
+	if script.Timeout != "" {
+		return parseTimeout(script.Timeout)
+	}
+	return parseTimeout(hm.config.Settings.Timeout)
+}
+
+// shellCommand runs line through sh with args available as "$@", which is
+// how generated hooks have always forwarded git's hook arguments.
+func shellCommand(line string, args []string) *exec.Cmd {
+	shellArgs := append([]string{"-c", line + ` "$@"`, "hooky"}, args...)
+	return exec.Command("sh", shellArgs...)
+}
@@ -0,0 +1,568 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupRunRepo creates a git repository containing configYAML as hooky.yaml
+// and changes into it for the duration of the test.
+func setupRunRepo(t *testing.T, configYAML string) string {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+
+	cmd := exec.Command("git", "init")
+	cmd.Dir = tmpDir
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+
+	configPath := filepath.Join(tmpDir, "hooky.yaml")
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	oldDir, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(oldDir) })
+	os.Chdir(tmpDir)
+
+	return tmpDir
+}
+
+func TestRunHook(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "first"
+      command: "echo first >> out.txt; true"
+      description: "First step"
+    - name: "second"
+      command: 'printf "%s\n" >> out.txt'
+      description: "Records hook arguments"
+`)
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-commit", []string{"a b", "c"}); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "out.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	expected := "first\na b\nc\n"
+	if string(content) != expected {
+		t.Errorf("Expected output %q, got %q", expected, string(content))
+	}
+}
+
+func TestRunHookStopsOnFailure(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "fails"
+      command: "exit 3"
+      description: "Failing step"
+    - name: "never"
+      command: "touch never-ran"
+      description: "Should not run"
+`)
+
+	hm := NewHookManager("hooky.yaml", false)
+	err := hm.RunHook("pre-commit", nil)
+	if err == nil {
+		t.Fatal("Expected RunHook to fail")
+	}
+	if !strings.Contains(err.Error(), "fails") {
+		t.Errorf("Expected error to name the failing step, got: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "never-ran")); !os.IsNotExist(err) {
+		t.Error("Steps after a failure should not run")
+	}
+}
+
+func TestRunHookUnconfigured(t *testing.T) {
+	setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "test"
+      command: "echo test"
+      description: "Test"
+`)
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-push", []string{"origin"}); err != nil {
+		t.Errorf("Running an unconfigured hook should be a no-op, got: %v", err)
+	}
+}
+
+func TestGroupSteps(t *testing.T) {
+	tests := []struct {
+		name     string
+		parallel []bool
+		expected []int
+	}{
+		{"all sequential", []bool{false, false, false}, []int{1, 1, 1}},
+		{"all parallel", []bool{true, true, true}, []int{3}},
+		{"parallel run between sequential steps", []bool{false, true, true, false}, []int{1, 2, 1}},
+		{"two parallel runs", []bool{true, true, false, true, true}, []int{2, 1, 2}},
+		{"single parallel step", []bool{false, true, false}, []int{1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var scripts []HookScript
+			for i, parallel := range tt.parallel {
+				scripts = append(scripts, HookScript{Name: fmt.Sprintf("step%d", i), Command: "true", Parallel: parallel})
+			}
+
+			groups := groupSteps(scripts)
+			if len(groups) != len(tt.expected) {
+				t.Fatalf("Expected %d groups, got %d", len(tt.expected), len(groups))
+			}
+			for i, group := range groups {
+				if len(group) != tt.expected[i] {
+					t.Errorf("Group %d: expected %d steps, got %d", i, tt.expected[i], len(group))
+				}
+			}
+		})
+	}
+}
+
+func TestRunHookParallel(t *testing.T) {
+	// Each step waits for the other's marker file, so they only succeed
+	// when they actually run at the same time.
+	setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "a"
+      command: "touch a; i=0; while [ ! -f b ] && [ $i -lt 50 ]; do sleep 0.1; i=$((i+1)); done; test -f b"
+      description: "Waits for b"
+      parallel: true
+    - name: "b"
+      command: "touch b; i=0; while [ ! -f a ] && [ $i -lt 50 ]; do sleep 0.1; i=$((i+1)); done; test -f a"
+      description: "Waits for a"
+      parallel: true
+settings:
+  parallel_workers: 2
+`)
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-commit", nil); err != nil {
+		t.Errorf("Parallel steps should run concurrently, got: %v", err)
+	}
+}
+
+func TestRunHookParallelReportsAllFailures(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "lint"
+      command: "exit 1"
+      description: "Fails"
+      parallel: true
+    - name: "format"
+      command: "touch format-ran"
+      description: "Passes"
+      parallel: true
+    - name: "test"
+      command: "exit 2"
+      description: "Fails"
+      parallel: true
+    - name: "after"
+      command: "touch after-ran"
+      description: "Sequential step after the group"
+`)
+
+	hm := NewHookManager("hooky.yaml", false)
+	err := hm.RunHook("pre-commit", nil)
+	if err == nil {
+		t.Fatal("Expected RunHook to fail")
+	}
+	if !strings.Contains(err.Error(), "lint, test") {
+		t.Errorf("Expected every failed step to be reported, got: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "format-ran")); err != nil {
+		t.Error("Passing steps in the group should still run")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "after-ran")); !os.IsNotExist(err) {
+		t.Error("Steps after a failed group should not run")
+	}
+}
+
+func TestRunHookTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts string
+	}{
+		{
+			name: "step timeout",
+			timeouts: `
+      timeout: "200ms"
+`,
+		},
+		{
+			name: "default timeout from settings",
+			timeouts: `
+settings:
+  timeout: "200ms"
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The background child would create "survived" if the process
+			// group were not killed along with the step.
+			tmpDir := setupRunRepo(t, `
+hooks:
+  pre-push:
+    - name: "hangs"
+      command: "(sleep 0.6; touch survived) & sleep 30"
+      description: "Never finishes"`+tt.timeouts)
+
+			hm := NewHookManager("hooky.yaml", false)
+
+			start := time.Now()
+			err := hm.RunHook("pre-push", nil)
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Timed out step took too long to stop: %s", elapsed)
+			}
+
+			var timeoutErr *TimeoutError
+			if !errors.As(err, &timeoutErr) {
+				t.Fatalf("Expected a TimeoutError, got: %v", err)
+			}
+			if timeoutErr.Timeout != 200*time.Millisecond {
+				t.Errorf("Expected timeout of 200ms, got %s", timeoutErr.Timeout)
+			}
+
+			time.Sleep(time.Second)
+			if _, err := os.Stat(filepath.Join(tmpDir, "survived")); !os.IsNotExist(err) {
+				t.Error("Children of a timed out step should be killed")
+			}
+		})
+	}
+}
+
+func TestRunHookStepTimeoutOverridesDefault(t *testing.T) {
+	setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "slow-but-allowed"
+      command: "sleep 0.3"
+      description: "Slower than the default timeout"
+      timeout: "10s"
+settings:
+  timeout: "100ms"
+`)
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-commit", nil); err != nil {
+		t.Errorf("Step timeout should override the default, got: %v", err)
+	}
+}
+
+// gitRun runs a git command in dir with a fixed identity, failing the test
+// on error.
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=hooky", "GIT_AUTHOR_EMAIL=hooky@example.com",
+		"GIT_COMMITTER_NAME=hooky", "GIT_COMMITTER_EMAIL=hooky@example.com",
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return string(output)
+}
+
+// writeFiles creates files (and their directories) under dir.
+func writeFiles(t *testing.T, dir string, files ...string) {
+	t.Helper()
+
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(file+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+}
+
+func TestRunHookFileFilters(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "go"
+      command: "touch go-ran"
+      description: "Only for Go files"
+      files: "*.go"
+    - name: "docs"
+      command: "touch docs-ran"
+      description: "Only for docs"
+      files: ["docs/**", "*.md"]
+      exclude: "docs/generated/"
+    - name: "always"
+      command: "touch always-ran"
+      description: "No filter"
+`)
+
+	writeFiles(t, tmpDir, "docs/guide.md", "main.go")
+	gitRun(t, tmpDir, "add", "docs/guide.md")
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-commit", nil); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+
+	for file, shouldExist := range map[string]bool{"go-ran": false, "docs-ran": true, "always-ran": true} {
+		_, err := os.Stat(filepath.Join(tmpDir, file))
+		if exists := err == nil; exists != shouldExist {
+			t.Errorf("Expected %s to exist: %v", file, shouldExist)
+		}
+	}
+}
+
+func TestRunHookFileFiltersExcludeAll(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "docs"
+      command: "touch docs-ran"
+      description: "Docs except generated ones"
+      files: "docs/"
+      exclude: "docs/generated/**"
+`)
+
+	writeFiles(t, tmpDir, "docs/generated/api.md")
+	gitRun(t, tmpDir, "add", ".")
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-commit", nil); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "docs-ran")); !os.IsNotExist(err) {
+		t.Error("Step should be skipped when every staged file is excluded")
+	}
+}
+
+func TestRunHookPrePushFileFilters(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-push:
+    - name: "go"
+      command: "touch go-ran"
+      description: "Only for Go files"
+      files: "*.go"
+    - name: "python"
+      command: "touch python-ran"
+      description: "Only for Python files"
+      files: "*.py"
+`)
+
+	remoteDir := t.TempDir()
+	gitRun(t, remoteDir, "init", "--bare")
+	gitRun(t, tmpDir, "remote", "add", "origin", remoteDir)
+
+	writeFiles(t, tmpDir, "app.py")
+	gitRun(t, tmpDir, "add", "app.py")
+	gitRun(t, tmpDir, "commit", "-m", "python")
+	gitRun(t, tmpDir, "push", "origin", "HEAD:refs/heads/main")
+
+	// Only the Go change is unpushed
+	writeFiles(t, tmpDir, "main.go")
+	gitRun(t, tmpDir, "add", "main.go")
+	gitRun(t, tmpDir, "commit", "-m", "go")
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-push", []string{"origin", remoteDir}); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "go-ran")); err != nil {
+		t.Error("Step matching pushed files should run")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "python-ran")); !os.IsNotExist(err) {
+		t.Error("Step matching only already-pushed files should be skipped")
+	}
+}
+
+func TestRunHookPassFilenames(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "lint"
+      command: "echo lint >> calls.txt"
+      description: "Receives Go files"
+      files: "*.go"
+      exclude: "*_test.go"
+      pass_filenames: true
+    - name: "none"
+      command: "touch none-ran"
+      description: "No matching files"
+      files: "*.py"
+      pass_filenames: true
+`)
+
+	writeFiles(t, tmpDir, "a.go", "b.go", "a_test.go", "docs/c.md", "pkg/d.go")
+	gitRun(t, tmpDir, "add", ".")
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-commit", []string{"ignored-hook-arg"}); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "calls.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read calls: %v", err)
+	}
+	if string(content) != "lint a.go b.go pkg/d.go\n" {
+		t.Errorf("Expected matching files as arguments, got %q", string(content))
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "none-ran")); !os.IsNotExist(err) {
+		t.Error("Step with pass_filenames should be skipped when no files match")
+	}
+}
+
+// stepArgLimit returns argLimit for a pre-commit step of the current
+// repository, counting the hook variables the step runs with. The staged
+// file list has a random name, so its path is counted at its longest.
+func stepArgLimit(t *testing.T, step, line string) int {
+	t.Helper()
+
+	root, err := repoRoot()
+	if err != nil {
+		t.Fatalf("Failed to find repository root: %v", err)
+	}
+	run := &hookRun{hookName: "pre-commit", root: root, filesList: filepath.Join(os.TempDir(), "hooky-files-4294967295")}
+	return argLimit(line, appendVars(os.Environ(), run.hookVars(HookScript{Name: step})))
+}
+
+func TestRunHookPassFilenamesBatches(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "lint"
+      command: "echo lint >> calls.txt"
+      description: "Receives files in batches"
+      pass_filenames: true
+`)
+
+	var files []string
+	for i := 0; i < 7; i++ {
+		files = append(files, fmt.Sprintf("file%d.go", i))
+	}
+	writeFiles(t, tmpDir, files...)
+	gitRun(t, tmpDir, "add", "file*.go")
+
+	// Leave room for two 9-byte file names per invocation
+	oldMax := maxArgLength
+	defer func() { maxArgLength = oldMax }()
+	maxArgLength = maxArgLength - stepArgLimit(t, "lint", "echo lint >> calls.txt") + 2*(9+1+8)
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-commit", nil); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "calls.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read calls: %v", err)
+	}
+
+	expected := "lint file0.go file1.go\nlint file2.go file3.go\nlint file4.go file5.go\nlint file6.go\n"
+	if string(content) != expected {
+		t.Errorf("Expected batched invocations %q, got %q", expected, string(content))
+	}
+}
+
+func TestRunHookPassFilenamesBatchFailure(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "lint"
+      command: 'echo "$1" >> calls.txt; case "$1" in bad.go) exit 1;; esac; true'
+      description: "Fails for one batch"
+      pass_filenames: true
+`)
+
+	writeFiles(t, tmpDir, "bad.go", "good.go")
+	gitRun(t, tmpDir, "add", "bad.go", "good.go")
+
+	oldMax := maxArgLength
+	defer func() { maxArgLength = oldMax }()
+	maxArgLength = maxArgLength - stepArgLimit(t, "lint", `echo "$1" >> calls.txt; case "$1" in bad.go) exit 1;; esac; true`) + 1
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-commit", nil); err == nil {
+		t.Error("Expected the step to fail when one batch fails")
+	}
+
+	content, _ := os.ReadFile(filepath.Join(tmpDir, "calls.txt"))
+	if string(content) != "bad.go\ngood.go\n" {
+		t.Errorf("Expected every batch to run, got %q", string(content))
+	}
+}
+
+func TestRunHookWorkingDir(t *testing.T) {
+	tmpDir := setupRunRepo(t, `
+hooks:
+  pre-commit:
+    - name: "api"
+      command: "echo api >> calls.txt"
+      description: "Runs in the API service with paths relative to it"
+      working_dir: "services/api"
+      files: "*.go"
+      exclude: "internal/"
+      pass_filenames: true
+    - name: "web"
+      command: "touch web-ran"
+      description: "No staged files below web/"
+      working_dir: "web"
+    - name: "root"
+      command: "echo root >> calls.txt"
+      description: "Runs from the repository root"
+      pass_filenames: true
+      files: "*.go"
+`)
+
+	writeFiles(t, tmpDir, "services/api/main.go", "services/api/cmd/serve.go", "services/api/internal/db.go", "services/apix/other.go", "web/README.md")
+	gitRun(t, tmpDir, "add", "services")
+
+	hm := NewHookManager("hooky.yaml", false)
+	if err := hm.RunHook("pre-commit", nil); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+
+	if content := readFile(t, tmpDir, "services/api/calls.txt"); content != "api cmd/serve.go main.go\n" {
+		t.Errorf("Expected paths relative to the working directory, got %q", content)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "web", "web-ran")); !os.IsNotExist(err) {
+		t.Error("Step should be skipped when no staged file is below its working directory")
+	}
+
+	expected := "root services/api/cmd/serve.go services/api/internal/db.go services/api/main.go services/apix/other.go\n"
+	if content := readFile(t, tmpDir, "calls.txt"); content != expected {
+		t.Errorf("Expected repository-relative paths for steps without working_dir, got %q", content)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// stashDirName is the directory inside the git directory where unstaged
+// changes are kept while pre-commit steps run.
+const stashDirName = "hooky-stash"
+
+// unstagedStash holds the unstaged and untracked changes set aside while
+// pre-commit steps run, so that the steps see exactly what is being
+// committed. Tracked changes are kept as a binary patch and untracked files
+// are moved into the stash directory.
+type unstagedStash struct {
+	root      string
+	dir       string
+	patch     string
+	untracked []string
+	// files are the tracked files with changes in patch
+	files []string
+}
+
+// stashUnstaged sets aside every change in the working tree that is not
+// staged, leaving the index untouched. It returns nil if there is nothing to
+// set aside.
+func stashUnstaged(root, gitDir string) (*unstagedStash, error) {
+	dir := filepath.Join(gitDir, stashDirName)
+	if _, err := os.Stat(dir); err == nil {
+		return nil, fmt.Errorf("changes from an interrupted run are still in %s; restore them with 'git apply %s' and by moving back the files in %s, then remove the directory",
+			dir, filepath.Join(dir, "unstaged.patch"), filepath.Join(dir, "untracked"))
+	}
+
+	diff, err := runGit(root, "diff", "--binary", "--no-color", "--no-ext-diff", "--ignore-submodules")
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := gitFileList(root, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	if diff == "" && len(untracked) == 0 {
+		return nil, nil
+	}
+
+	files, err := gitFileList(root, "diff", "--name-only", "-z", "--ignore-submodules")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create stash directory: %w", err)
+	}
+
+	stash := &unstagedStash{root: root, dir: dir, files: files}
+
+	if diff != "" {
+		stash.patch = filepath.Join(dir, "unstaged.patch")
+		if err := os.WriteFile(stash.patch, []byte(diff), 0644); err != nil {
+			os.RemoveAll(dir)
+			return nil, fmt.Errorf("failed to save unstaged changes: %w", err)
+		}
+	}
+
+	for _, file := range untracked {
+		if err := moveFile(filepath.Join(root, file), filepath.Join(dir, "untracked", file)); err != nil {
+			stash.restore()
+			return nil, fmt.Errorf("failed to set aside untracked file %s: %w", file, err)
+		}
+		stash.untracked = append(stash.untracked, file)
+		removeEmptyParents(filepath.Join(root, file), root)
+	}
+
+	if stash.patch != "" {
+		if _, err := runGit(root, "checkout", "--", ":/"); err != nil {
+			stash.restore()
+			return nil, fmt.Errorf("failed to remove unstaged changes: %w", err)
+		}
+	}
+
+	fmt.Printf("Stashed unstaged changes to %s\n", dir)
+	return stash, nil
+}
+
+// restore puts the stashed changes back. If steps modified files in the
+// working tree in a way that conflicts with the unstaged changes, those
+// modifications are discarded in favour of the user's. Modifications the
+// steps staged are kept, and as the patch was made against the old index it
+// may then no longer apply; the changes stay in the stash directory, which
+// is only removed once everything has been restored.
+func (s *unstagedStash) restore() error {
+	var problems []string
+
+	if s.patch != "" {
+		if _, err := runGit(s.root, "apply", "--whitespace=nowarn", s.patch); err != nil {
+			fmt.Printf("Unstaged changes conflict with changes made by the hook; discarding the hook's changes\n")
+			if _, err := runGit(s.root, "checkout", "--", ":/"); err != nil {
+				problems = append(problems, err.Error())
+			} else if _, err := runGit(s.root, "apply", "--whitespace=nowarn", s.patch); err != nil {
+				problems = append(problems, fmt.Sprintf("could not reapply %s: %v", s.patch, err))
+			}
+		}
+	}
+
+	for _, file := range s.untracked {
+		dest := filepath.Join(s.root, file)
+		if _, err := os.Lstat(dest); err == nil {
+			problems = append(problems, fmt.Sprintf("untracked file %s was recreated by the hook; your version is in %s", file, filepath.Join(s.dir, "untracked", file)))
+			continue
+		}
+		if err := moveFile(filepath.Join(s.dir, "untracked", file), dest); err != nil {
+			problems = append(problems, fmt.Sprintf("could not restore untracked file %s: %v", file, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("failed to restore unstaged changes, they are kept in %s:\n  %s", s.dir, strings.Join(problems, "\n  "))
+	}
+
+	if err := os.RemoveAll(s.dir); err != nil {
+		return fmt.Errorf("failed to remove stash directory: %w", err)
+	}
+	fmt.Printf("Restored unstaged changes\n")
+	return nil
+}
+
+// moveFile renames src to dest, creating dest's parent directories.
+func moveFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return os.Rename(src, dest)
+}
+
+// removeEmptyParents removes the now empty directories that contained path,
+// stopping at root or at the first directory that is not empty.
+func removeEmptyParents(path, root string) {
+	for dir := filepath.Dir(path); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupStashRepo creates a repository with a committed file "f", a staged
+// change to its first line, an unstaged change to its last line and an
+// untracked file in a new directory.
+func setupStashRepo(t *testing.T) (root, gitDir string) {
+	t.Helper()
+
+	root = t.TempDir()
+	gitRun(t, root, "init")
+	writeFile(t, root, "f", "l1\nl2\nl3\nl4\nl5\n")
+	gitRun(t, root, "add", "f")
+	gitRun(t, root, "commit", "-m", "init")
+
+	writeFile(t, root, "f", "STAGED\nl2\nl3\nl4\nl5\n")
+	gitRun(t, root, "add", "f")
+	writeFile(t, root, "f", "STAGED\nl2\nl3\nl4\nUNSTAGED\n")
+	writeFile(t, root, "newdir/untracked.txt", "untracked\n")
+
+	return root, filepath.Join(root, ".git")
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	return string(content)
+}
+
+func TestStashUnstaged(t *testing.T) {
+	root, gitDir := setupStashRepo(t)
+
+	stash, err := stashUnstaged(root, gitDir)
+	if err != nil {
+		t.Fatalf("stashUnstaged failed: %v", err)
+	}
+	if stash == nil {
+		t.Fatal("Expected changes to be stashed")
+	}
+
+	if content := readFile(t, root, "f"); content != "STAGED\nl2\nl3\nl4\nl5\n" {
+		t.Errorf("Working tree should match the index while stashed, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(root, "newdir")); !os.IsNotExist(err) {
+		t.Error("Untracked files and their directories should be set aside")
+	}
+
+	if err := stash.restore(); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+
+	if content := readFile(t, root, "f"); content != "STAGED\nl2\nl3\nl4\nUNSTAGED\n" {
+		t.Errorf("Unstaged changes should be restored, got %q", content)
+	}
+	if content := readFile(t, root, "newdir/untracked.txt"); content != "untracked\n" {
+		t.Errorf("Untracked file should be restored, got %q", content)
+	}
+	if staged := gitRun(t, root, "diff", "--cached", "--name-only"); staged != "f\n" {
+		t.Errorf("Index should be unchanged, got %q", staged)
+	}
+	if unstaged := gitRun(t, root, "diff"); !strings.Contains(unstaged, "+UNSTAGED") || strings.Contains(unstaged, "+STAGED") {
+		t.Errorf("Only the unstaged change should be unstaged, got:\n%s", unstaged)
+	}
+	if _, err := os.Stat(filepath.Join(gitDir, stashDirName)); !os.IsNotExist(err) {
+		t.Error("Stash directory should be removed after restoring")
+	}
+}
+
+func TestStashUnstagedConflictingChanges(t *testing.T) {
+	root, gitDir := setupStashRepo(t)
+
+	stash, err := stashUnstaged(root, gitDir)
+	if err != nil {
+		t.Fatalf("stashUnstaged failed: %v", err)
+	}
+
+	// A step rewrites the line that also has an unstaged change
+	writeFile(t, root, "f", "STAGED\nl2\nl3\nl4\nFORMATTED\n")
+
+	if err := stash.restore(); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+
+	if content := readFile(t, root, "f"); content != "STAGED\nl2\nl3\nl4\nUNSTAGED\n" {
+		t.Errorf("User's unstaged changes should win over the hook's, got %q", content)
+	}
+}
+
+func TestStashUnstagedNothingToStash(t *testing.T) {
+	root := t.TempDir()
+	gitRun(t, root, "init")
+	writeFile(t, root, "f", "content\n")
+	gitRun(t, root, "add", "f")
+
+	stash, err := stashUnstaged(root, filepath.Join(root, ".git"))
+	if err != nil {
+		t.Fatalf("stashUnstaged failed: %v", err)
+	}
+	if stash != nil {
+		t.Error("Expected nothing to be stashed when everything is staged")
+	}
+}
+
+func TestStashUnstagedLeftoverStash(t *testing.T) {
+	root, gitDir := setupStashRepo(t)
+
+	if err := os.MkdirAll(filepath.Join(gitDir, stashDirName), 0755); err != nil {
+		t.Fatalf("Failed to create leftover stash: %v", err)
+	}
+
+	_, err := stashUnstaged(root, gitDir)
+	if err == nil || !strings.Contains(err.Error(), "interrupted run") {
+		t.Errorf("Expected an error about the leftover stash, got: %v", err)
+	}
+}
+
+func TestRunHookStashUnstaged(t *testing.T) {
+	root, _ := setupStashRepo(t)
+	writeFile(t, root, "hooky.yaml", `
+hooks:
+  pre-commit:
+    - name: "sees-commit"
+      command: "grep -q UNSTAGED f && exit 1; test ! -e newdir/untracked.txt && test ! -e hooky.yaml"
+      description: "Only staged content is visible"
+    - name: "fails"
+      command: "exit 1"
+      description: "Restoring must survive failures"
+settings:
+  stash_unstaged: true
+`)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(root)
+
+	hm := NewHookManager("hooky.yaml", false)
+	err := hm.RunHook("pre-commit", nil)
+
+	var hookErr *HookError
+	if !errors.As(err, &hookErr) || len(hookErr.Failed) != 1 || hookErr.Failed[0].Step != "fails" {
+		t.Fatalf("Expected only the failing step to fail, got: %v", err)
+	}
+
+	if content := readFile(t, root, "f"); content != "STAGED\nl2\nl3\nl4\nUNSTAGED\n" {
+		t.Errorf("Unstaged changes should be restored after a failure, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(root, "newdir/untracked.txt")); err != nil {
+		t.Error("Untracked file should be restored after a failure")
+	}
+}
+
+func TestRunHookStashRestoreFailure(t *testing.T) {
+	root, _ := setupStashRepo(t)
+	writeFile(t, root, "hooky.yaml", `
+hooks:
+  pre-commit:
+    - name: "recreates"
+      command: "mkdir -p newdir && echo hook > newdir/untracked.txt && echo recreated"
+      description: "Recreates the stashed untracked file"
+settings:
+  stash_unstaged: true
+`)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(root)
+
+	hm := NewHookManager("hooky.yaml", false)
+	err := hm.RunHook("pre-commit", nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to restore unstaged changes") {
+		t.Fatalf("Expected the failed restore to fail the hook, got: %v", err)
+	}
+	if content := readFile(t, root, filepath.Join(".git", stashDirName, "untracked", "newdir", "untracked.txt")); content != "untracked\n" {
+		t.Errorf("Expected the stashed file to be kept, got %q", content)
+	}
+}